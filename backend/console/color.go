@@ -1,23 +1,42 @@
+//go:build !wasm
 // +build !wasm
 
 package console
 
-import "github.com/fatih/color"
+import (
+	"io"
+	"os"
 
-var fatalp = color.New(color.FgRed).PrintFunc()
-var fatalf = color.New(color.FgRed).PrintfFunc()
+	"github.com/fatih/color"
+	"github.com/mattn/go-colorable"
+	"github.com/mattn/go-isatty"
+)
 
-var errorp = color.New(color.FgRed).PrintFunc()
-var errorf = color.New(color.FgRed).PrintfFunc()
+func newPaint(attrs []int) paint {
+	if len(attrs) == 0 {
+		return nil
+	}
 
-var warnp = color.New(color.FgYellow).PrintFunc()
-var warnf = color.New(color.FgYellow).PrintfFunc()
+	values := make([]color.Attribute, 0, len(attrs))
 
-var infop = color.New(color.FgWhite).PrintFunc()
-var infof = color.New(color.FgWhite).PrintfFunc()
+	for _, attr := range attrs {
+		values = append(values, color.Attribute(attr))
+	}
 
-var debugp = color.New(color.FgCyan).PrintFunc()
-var debugf = color.New(color.FgCyan).PrintfFunc()
+	c := color.New(values...)
 
-var tracep = color.New(color.FgBlue).PrintFunc()
-var tracef = color.New(color.FgBlue).PrintfFunc()
+	// color is decided per output stream by the console backend
+	c.EnableColor()
+
+	return func(text string) string {
+		return c.Sprint(text)
+	}
+}
+
+func isTerminal(file *os.File) bool {
+	return isatty.IsTerminal(file.Fd()) || isatty.IsCygwinTerminal(file.Fd())
+}
+
+func newWriter(file *os.File) io.Writer {
+	return colorable.NewColorable(file)
+}
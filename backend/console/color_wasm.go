@@ -1,21 +1,18 @@
 package console
 
-import "fmt"
+import (
+	"io"
+	"os"
+)
 
-var fatalp = fmt.Sprint
-var fatalf = fmt.Sprintf
+func newPaint(attrs []int) paint {
+	return nil
+}
 
-var errorp = fmt.Sprint
-var errorf = fmt.Sprintf
+func isTerminal(file *os.File) bool {
+	return false
+}
 
-var warnp = fmt.Sprint
-var warnf = fmt.Sprintf
-
-var infop = fmt.Sprint
-var infof = fmt.Sprintf
-
-var debugp = fmt.Sprint
-var debugf = fmt.Sprintf
-
-var tracep = fmt.Sprint
-var tracef = fmt.Sprintf
+func newWriter(file *os.File) io.Writer {
+	return file
+}
@@ -2,9 +2,11 @@ package console
 
 import (
 	"fmt"
-	"strings"
+	"io"
+	"os"
 	"time"
 
+	"github.com/libs4go/errors"
 	"github.com/libs4go/scf4go"
 	"github.com/libs4go/slf4go"
 )
@@ -14,34 +16,85 @@ type formatter struct {
 	Output    string `json:"output"`
 }
 
+// output streams
+const (
+	streamStdout = "stdout"
+	streamStderr = "stderr"
+	streamSplit  = "split" // stderr for WARN and ERROR, stdout for others
+)
+
+type output struct {
+	writer io.Writer
+	color  bool
+}
+
+func newOutput(file *os.File, mode string) *output {
+	return &output{
+		writer: newWriter(file),
+		color:  colorEnabled(mode, file),
+	}
+}
+
 type consoleImpl struct {
 	formatter *formatter
+	layout    layout
+	format    slf4go.Formatter // formatter selected with 'format', overrides formatter output
+	pretty    *pretty          // pretty mode selected with 'formatter: mode: pretty'
+	theme     *theme
+	stream    string
+	stdout    *output
+	stderr    *output
+}
+
+func (console *consoleImpl) output(level slf4go.Level) *output {
+	switch console.stream {
+	case streamStderr:
+		return console.stderr
+	case streamSplit:
+		if level >= slf4go.WARN {
+			return console.stderr
+		}
+	}
+
+	return console.stdout
 }
 
 func (console *consoleImpl) Send(entry *slf4go.EventEntry) {
 
-	message := console.formatter.Output
-
-	message = strings.ReplaceAll(message, "@line", fmt.Sprintf("%d", entry.Line))
-	message = strings.ReplaceAll(message, "@func", entry.Function)
-	message = strings.ReplaceAll(message, "@t", entry.Timestamp.Format(console.formatter.Timestamp))
-	message = strings.ReplaceAll(message, "@l", entry.Level.String())
-	message = strings.ReplaceAll(message, "@m", entry.Message)
-	message = strings.ReplaceAll(message, "@s", entry.Source)
-	// message = strings.ReplaceAll(message, "@f", "..."+entry.File[len(entry.File)/2:])
-
-	switch entry.Level {
-	case slf4go.TRACE:
-		tracep(message, "\n")
-	case slf4go.DEBUG:
-		debugp(message, "\n")
-	case slf4go.INFO:
-		infop(message, "\n")
-	case slf4go.WARN:
-		warnp(message, "\n")
-	case slf4go.ERROR:
-		errorp(message, "\n")
+	output := console.output(entry.Level)
+
+	var palette *palette
+
+	if output.color {
+		palette = console.theme.palette(entry.Level)
 	}
+
+	message, ok := console.message(entry, palette)
+
+	if !ok {
+		return
+	}
+
+	io.WriteString(output.writer, message+"\n")
+}
+
+func (console *consoleImpl) message(entry *slf4go.EventEntry, palette *palette) (string, bool) {
+	if console.format != nil {
+		buff, err := console.format.Format(entry)
+
+		if err != nil {
+			println(fmt.Sprintf("format event entry error %s", err))
+			return "", false
+		}
+
+		return palette.paint("", string(buff)), true
+	}
+
+	if console.pretty != nil {
+		return console.pretty.render(entry, palette), true
+	}
+
+	return console.layout.render(entry, console.formatter.Timestamp, palette), true
 }
 
 func (console *consoleImpl) Sync() {
@@ -54,15 +107,80 @@ func (console *consoleImpl) Config(config scf4go.Config) error {
 
 	console.formatter.Timestamp = config.Get("formatter", "timestamp").String(time.RFC3339)
 	console.formatter.Output = config.Get("formatter", "output").String(defaultOutput)
+	console.layout = parseLayout(console.formatter.Output)
+	console.format = nil
+	console.pretty = nil
+
+	switch mode := config.Get("formatter", "mode").String(modeLayout); mode {
+	case modeLayout:
+	case modePretty:
+		pretty, err := newPretty(config, console.formatter.Timestamp)
+
+		if err != nil {
+			return err
+		}
+
+		console.pretty = pretty
+	default:
+		return errors.Wrap(slf4go.ErrArgs, "unknown console formatter mode %s", mode)
+	}
+
+	console.stream = config.Get("stream").String(streamStdout)
+
+	switch console.stream {
+	case streamStdout, streamStderr, streamSplit:
+	default:
+		return errors.Wrap(slf4go.ErrArgs, "unknown console stream %s", console.stream)
+	}
+
+	mode := config.Get("color").String(colorAuto)
+
+	switch mode {
+	case colorAuto, colorAlways, colorNever:
+	default:
+		return errors.Wrap(slf4go.ErrArgs, "unknown console color mode %s", mode)
+	}
+
+	console.stdout = newOutput(os.Stdout, mode)
+	console.stderr = newOutput(os.Stderr, mode)
+
+	theme, err := newTheme(config)
+
+	if err != nil {
+		return err
+	}
+
+	console.theme = theme
+
+	if config.Get("format").String("") != "" {
+		format, err := slf4go.NewFormatter(config, "")
+
+		if err != nil {
+			return err
+		}
+
+		console.format = format
+	}
 
 	return nil
 }
 
-func init() {
-	slf4go.RegisterBackend("console", &consoleImpl{
+func newConsole() *consoleImpl {
+	return &consoleImpl{
 		formatter: &formatter{
 			Timestamp: time.RFC3339,
 			Output:    defaultOutput,
 		},
+		layout: parseLayout(defaultOutput),
+		theme:  defaultTheme(),
+		stream: streamStdout,
+		stdout: newOutput(os.Stdout, colorAuto),
+		stderr: newOutput(os.Stderr, colorAuto),
+	}
+}
+
+func init() {
+	slf4go.RegisterBackendType("console", func(name string) slf4go.Backend {
+		return newConsole()
 	})
 }
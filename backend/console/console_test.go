@@ -1,11 +1,15 @@
 package console
 
 import (
+	"bytes"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/libs4go/scf4go"
 	_ "github.com/libs4go/scf4go/codec" //
 	"github.com/libs4go/scf4go/reader/file"
+	"github.com/libs4go/scf4go/reader/memory"
 	"github.com/libs4go/slf4go"
 	"github.com/stretchr/testify/require"
 )
@@ -26,3 +30,145 @@ func TestLog(t *testing.T) {
 
 	slf4go.Get("test").D("test a {@one}", &hello{A: "a"})
 }
+
+func TestLayout(t *testing.T) {
+	entry := &slf4go.EventEntry{
+		Timestamp: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Level:     slf4go.WARN,
+		Message:   "mail @l sent to {@s}",
+		Template:  "mail @l sent to {@to}",
+		Source:    "github.com/libs4go/slf4go/backend/console",
+		Attrs: map[string]interface{}{
+			"@to":    "a@b.c",
+			"@count": 2,
+		},
+		Line:     10,
+		Function: "main.main",
+	}
+
+	render := func(pattern string) string {
+		return parseLayout(pattern).render(entry, time.RFC3339, nil)
+	}
+
+	require.Equal(t, "2020-01-02T03:04:05Z warn mail @l sent to {@s}", render("@t @l @m"))
+	require.Equal(t, "03:04:05 |WARN | main.main:10", render("@t{15:04:05} |@-5l{upper}| @func:@line"))
+	require.Equal(t, "  warn|a@b.c|", render("@6l|@a.to|@a.missing"))
+	require.Equal(t, "count=2 to=a@b.c.a@b.c.", render("@a.@a.to."))
+	require.Equal(t, "sole|ma", render("@.4s|@.-2m"))
+	require.Equal(t, "g.c/l/s/b/console", render("@s{15}"))
+	require.Equal(t, "g.c/l/slf4go/backend/console", render("@s{30}"))
+	require.Equal(t, "console", render("@s{0}"))
+	require.Equal(t, "@ @x @-3", render("@@ @x @-3"))
+
+	require.Equal(t, "c.e.Foo", abbreviate("com.example.Foo", 10))
+	require.Equal(t, "com.example.Foo", abbreviate("com.example.Foo", 20))
+}
+
+func TestStream(t *testing.T) {
+	config := scf4go.New()
+
+	err := config.Load(memory.New(memory.Data(`
+stream: split
+color: always
+formatter:
+  output: "@l @s: @m @a"
+theme:
+  warn: bold,hi_yellow
+  logger: green
+  attr: bg_blue
+`, "yaml")))
+
+	require.NoError(t, err)
+
+	console := newConsole()
+
+	require.NoError(t, console.Config(config))
+
+	var stdout, stderr bytes.Buffer
+
+	console.stdout = &output{writer: &stdout}
+	console.stderr = &output{writer: &stderr, color: true}
+
+	entry := &slf4go.EventEntry{
+		Level:   slf4go.INFO,
+		Message: "hello",
+		Source:  "test",
+		Attrs:   map[string]interface{}{"@id": 1},
+	}
+
+	console.Send(entry)
+
+	entry.Level = slf4go.WARN
+
+	console.Send(entry)
+
+	require.Equal(t, "info test: hello id=1\n", stdout.String())
+	require.Equal(t, "\x1b[1;93mwarn\x1b[0m\x1b[1;93m \x1b[0m\x1b[32mtest\x1b[0m\x1b[1;93m: \x1b[0m\x1b[1;93mhello\x1b[0m\x1b[1;93m \x1b[0m\x1b[1;93mid=\x1b[0m\x1b[44m1\x1b[0m\n", stderr.String())
+
+	_, err = parseColor("hi_bold")
+	require.Error(t, err)
+
+	os.Setenv("NO_COLOR", "1")
+	defer os.Unsetenv("NO_COLOR")
+
+	require.False(t, colorEnabled(colorAuto, os.Stdout))
+	require.True(t, colorEnabled(colorAlways, os.Stdout))
+}
+
+func TestPretty(t *testing.T) {
+	config := scf4go.New()
+
+	err := config.Load(memory.New(memory.Data(`
+formatter:
+  mode: pretty
+  time: delta
+  stack_lines: 2
+`, "yaml")))
+
+	require.NoError(t, err)
+
+	pretty, err := newPretty(config, time.RFC3339)
+	require.NoError(t, err)
+
+	now := time.Now()
+
+	entry := &slf4go.EventEntry{
+		Timestamp: now,
+		Level:     slf4go.WARN,
+		Message:   `user "alice" login failed 3 times`,
+		Template:  "user {@name} login failed {@count} times",
+		Source:    "auth",
+		Attrs: map[string]interface{}{
+			"@name":  "alice",
+			"@count": 3,
+			"@stack": "goroutine 1 [running]:\nmain.main()\n\t/main.go:10\nruntime.main()",
+		},
+	}
+
+	require.Equal(t, "+0.000s WARN  auth user \"alice\" login failed 3 times\n"+
+		"    count = 3\n"+
+		"    name  = alice\n"+
+		"    stack = goroutine 1 [running]:\n"+
+		"            main.main()\n"+
+		"            ... 2 more lines", pretty.render(entry, nil))
+
+	entry.Timestamp = now.Add(1500 * time.Millisecond)
+	pretty.attrs = attrsJSON
+
+	require.Equal(t, "+1.500s WARN  auth user \"alice\" login failed 3 times\n"+
+		"    {\n"+
+		"      \"count\": 3,\n"+
+		"      \"name\": \"alice\",\n"+
+		"      \"stack\": \"goroutine 1 [running]:\\nmain.main()\\n... 2 more lines\"\n"+
+		"    }", pretty.render(entry, nil))
+
+	palette := &palette{tokens: map[string]paint{
+		"v": func(text string) string { return "[" + text + "]" },
+	}}
+
+	require.Equal(t, `user ["alice"] login failed [3] times`, pretty.highlightMessage(entry, palette))
+
+	entry.Template = "{@name}{@count}"
+
+	require.Equal(t, entry.Message, pretty.highlightMessage(entry, palette))
+}
@@ -0,0 +1,342 @@
+package console
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/libs4go/slf4go"
+)
+
+// layout console output layout parsed from pattern like '@t |@-5l{upper}| @s{20} @m @a'
+//
+// token syntax is '@[-][min][.[-]max]name[{option}]', '-' left-justify the value padded to min runes,
+// '.max' truncate the value from the beginning and '.-max' from the end like logback, option is
+// a time layout for @t, the target length for @s abbreviation and 'upper'/'lower' for other tokens,
+// '@@' is the literal '@'
+type layout []*segment
+
+type segment struct {
+	literal     string
+	token       string
+	key         string // attribute key of @a.key
+	option      string
+	leftAlign   bool
+	min         int
+	max         int // zero means no truncation
+	truncateEnd bool
+}
+
+// layoutTokens entry fields available in layout
+var layoutTokens = map[string]bool{
+	"t":    true, // timestamp
+	"l":    true, // level
+	"m":    true, // message
+	"mt":   true, // message template
+	"i":    true, // event type
+	"s":    true, // source logger name
+	"f":    true, // file
+	"line": true,
+	"func": true,
+	"a":    true, // all attributes or @a.key attribute
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z'
+}
+
+func isKeyChar(c byte) bool {
+	return isDigit(c) || isLetter(c) || (c >= 'A' && c <= 'Z') || c == '_' || c == '.' || c == '-'
+}
+
+func readNumber(pattern string, i int) (int, int) {
+	start := i
+
+	for i < len(pattern) && isDigit(pattern[i]) {
+		i++
+	}
+
+	if start == i {
+		return 0, i
+	}
+
+	n, _ := strconv.Atoi(pattern[start:i])
+
+	return n, i
+}
+
+// parseToken parse token starting after '@', return nil if it is not a token
+func parseToken(pattern string, i int) (*segment, int) {
+	seg := &segment{}
+
+	if i < len(pattern) && pattern[i] == '-' {
+		seg.leftAlign = true
+		i++
+	}
+
+	seg.min, i = readNumber(pattern, i)
+
+	if i < len(pattern) && pattern[i] == '.' {
+		i++
+
+		if i < len(pattern) && pattern[i] == '-' {
+			seg.truncateEnd = true
+			i++
+		}
+
+		seg.max, i = readNumber(pattern, i)
+	}
+
+	start := i
+
+	for i < len(pattern) && isLetter(pattern[i]) {
+		i++
+	}
+
+	seg.token = pattern[start:i]
+
+	if !layoutTokens[seg.token] {
+		return nil, i
+	}
+
+	if seg.token == "a" && i+1 < len(pattern) && pattern[i] == '.' && isKeyChar(pattern[i+1]) {
+		start = i + 1
+		i = start
+
+		for i < len(pattern) && isKeyChar(pattern[i]) {
+			i++
+		}
+
+		// a trailing '.' belongs to the literal text
+		for i > start && pattern[i-1] == '.' {
+			i--
+		}
+
+		seg.key = pattern[start:i]
+	}
+
+	if i < len(pattern) && pattern[i] == '{' {
+		if end := strings.IndexByte(pattern[i:], '}'); end != -1 {
+			seg.option = pattern[i+1 : i+end]
+			i += end + 1
+		}
+	}
+
+	return seg, i
+}
+
+func parseLayout(pattern string) layout {
+	var result layout
+	var literal strings.Builder
+
+	flush := func() {
+		if literal.Len() > 0 {
+			result = append(result, &segment{literal: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(pattern); {
+		if pattern[i] != '@' {
+			literal.WriteByte(pattern[i])
+			i++
+			continue
+		}
+
+		if i+1 < len(pattern) && pattern[i+1] == '@' {
+			literal.WriteByte('@')
+			i += 2
+			continue
+		}
+
+		seg, next := parseToken(pattern, i+1)
+
+		if seg == nil {
+			literal.WriteString(pattern[i:next])
+			i = next
+			continue
+		}
+
+		flush()
+
+		result = append(result, seg)
+		i = next
+	}
+
+	flush()
+
+	return result
+}
+
+// abbreviate shorten logger name like logback %logger{length}, leading segments separated by
+// '.' or '/' are reduced to their first letter until the name fits, the last segment is kept
+func abbreviate(name string, length int) string {
+	if len(name) <= length {
+		return name
+	}
+
+	index := strings.LastIndexAny(name, "./")
+
+	if length == 0 || index == -1 {
+		return name[index+1:]
+	}
+
+	var parts []string
+
+	start := 0
+
+	for i := 0; i < len(name); i++ {
+		if name[i] == '.' || name[i] == '/' {
+			parts = append(parts, name[start:i+1])
+			start = i + 1
+		}
+	}
+
+	parts = append(parts, name[start:])
+
+	total := len(name)
+
+	for i := 0; i < len(parts)-1 && total > length; i++ {
+		part := parts[i]
+
+		if len(part) <= 2 {
+			continue
+		}
+
+		_, size := utf8.DecodeRuneInString(part)
+
+		total -= len(part) - size - 1
+		parts[i] = part[:size] + part[len(part)-1:]
+	}
+
+	return strings.Join(parts, "")
+}
+
+// renderAttrs render attributes as sorted key=value pairs, keys and values are painted separately
+func renderAttrs(attrs map[string]interface{}, keyPaint paint, valuePaint paint) string {
+	keys := make([]string, 0, len(attrs))
+
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var buff strings.Builder
+
+	for _, name := range keys {
+		if buff.Len() > 0 {
+			buff.WriteString(keyPaint.apply(" "))
+		}
+
+		buff.WriteString(keyPaint.apply(strings.TrimPrefix(name, "@") + "="))
+		buff.WriteString(valuePaint.apply(slf4go.AttrString(attrs[name])))
+	}
+
+	return buff.String()
+}
+
+func (seg *segment) value(entry *slf4go.EventEntry, timestamp string) string {
+	switch seg.token {
+	case "t":
+		if seg.option != "" {
+			return entry.Timestamp.Format(seg.option)
+		}
+
+		return entry.Timestamp.Format(timestamp)
+	case "l":
+		return entry.Level.String()
+	case "m":
+		return entry.Message
+	case "mt":
+		return entry.Template
+	case "i":
+		return entry.EventType
+	case "s":
+		if length, err := strconv.Atoi(seg.option); err == nil {
+			return abbreviate(entry.Source, length)
+		}
+
+		return entry.Source
+	case "f":
+		return entry.File
+	case "line":
+		return fmt.Sprintf("%d", entry.Line)
+	case "func":
+		return entry.Function
+	case "a":
+		if seg.key == "" {
+			return renderAttrs(entry.Attrs, nil, nil)
+		}
+
+		value, ok := entry.Attrs["@"+seg.key]
+
+		if !ok {
+			return ""
+		}
+
+		return slf4go.AttrString(value)
+	}
+
+	return ""
+}
+
+func (seg *segment) render(buff *strings.Builder, entry *slf4go.EventEntry, timestamp string, palette *palette) {
+	if seg.token == "" {
+		buff.WriteString(palette.paint("", seg.literal))
+		return
+	}
+
+	value := seg.value(entry, timestamp)
+
+	switch seg.option {
+	case "upper":
+		value = strings.ToUpper(value)
+	case "lower":
+		value = strings.ToLower(value)
+	}
+
+	if seg.max > 0 {
+		if runes := []rune(value); len(runes) > seg.max {
+			if seg.truncateEnd {
+				value = string(runes[:seg.max])
+			} else {
+				value = string(runes[len(runes)-seg.max:])
+			}
+		}
+	}
+
+	padding := seg.min - utf8.RuneCountInString(value)
+
+	if padding > 0 && !seg.leftAlign {
+		buff.WriteString(strings.Repeat(" ", padding))
+	}
+
+	if seg.token == "a" && seg.key == "" && seg.option == "" && seg.max == 0 && palette != nil {
+		// only attribute values are painted with the attr color
+		buff.WriteString(renderAttrs(entry.Attrs, palette.base, palette.tokens["a"]))
+	} else {
+		buff.WriteString(palette.paint(seg.token, value))
+	}
+
+	if padding > 0 && seg.leftAlign {
+		buff.WriteString(strings.Repeat(" ", padding))
+	}
+}
+
+// render entry with palette, nil palette renders plain text
+func (l layout) render(entry *slf4go.EventEntry, timestamp string, palette *palette) string {
+	var buff strings.Builder
+
+	for _, seg := range l {
+		seg.render(&buff, entry, timestamp, palette)
+	}
+
+	return buff.String()
+}
@@ -0,0 +1,291 @@
+package console
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/scf4go"
+	"github.com/libs4go/slf4go"
+)
+
+// formatter modes
+const (
+	modeLayout = "layout"
+	modePretty = "pretty"
+)
+
+// pretty time modes
+const (
+	timeAbsolute = "absolute"
+	timeRelative = "relative" // since process start
+	timeDelta    = "delta"    // since the previous line
+)
+
+// pretty attribute modes
+const (
+	attrsKV   = "kv"
+	attrsJSON = "json"
+	attrsNone = "none"
+)
+
+var processStart = time.Now()
+
+var placeholderRegx = regexp.MustCompile(`{@[a-zA-Z0-9]*}`)
+
+const prettyIndent = "    "
+
+func attrName(key string) string {
+	return strings.TrimPrefix(key, "@")
+}
+
+// pretty developer friendly console output, attributes are rendered beneath the message
+type pretty struct {
+	timestamp  string
+	time       string
+	attrs      string
+	stackLines int // lines kept of folded stack traces, 0 disables folding
+	highlight  bool
+	mutex      sync.Mutex
+	last       time.Time
+}
+
+func newPretty(config scf4go.Config, timestamp string) (*pretty, error) {
+	pretty := &pretty{
+		timestamp:  timestamp,
+		time:       config.Get("formatter", "time").String(timeAbsolute),
+		attrs:      config.Get("formatter", "attrs").String(attrsKV),
+		stackLines: config.Get("formatter", "stack_lines").Int(10),
+		highlight:  config.Get("formatter", "highlight").Bool(true),
+	}
+
+	switch pretty.time {
+	case timeAbsolute, timeRelative, timeDelta:
+	default:
+		return nil, errors.Wrap(slf4go.ErrArgs, "unknown console time mode %s", pretty.time)
+	}
+
+	switch pretty.attrs {
+	case attrsKV, attrsJSON, attrsNone:
+	default:
+		return nil, errors.Wrap(slf4go.ErrArgs, "unknown console attrs mode %s", pretty.attrs)
+	}
+
+	return pretty, nil
+}
+
+func formatSeconds(duration time.Duration) string {
+	return fmt.Sprintf("+%.3fs", duration.Seconds())
+}
+
+func (pretty *pretty) timeOf(entry *slf4go.EventEntry) string {
+	switch pretty.time {
+	case timeRelative:
+		return formatSeconds(entry.Timestamp.Sub(processStart))
+	case timeDelta:
+		pretty.mutex.Lock()
+		defer pretty.mutex.Unlock()
+
+		var delta time.Duration
+
+		if !pretty.last.IsZero() {
+			delta = entry.Timestamp.Sub(pretty.last)
+		}
+
+		pretty.last = entry.Timestamp
+
+		return formatSeconds(delta)
+	}
+
+	return entry.Timestamp.Format(pretty.timestamp)
+}
+
+// highlightMessage paint values substituted into the template, the message is painted as is
+// if it doesn't match the template
+func (pretty *pretty) highlightMessage(entry *slf4go.EventEntry, palette *palette) string {
+	if !pretty.highlight || palette == nil || entry.Template == "" {
+		return palette.paint("m", entry.Message)
+	}
+
+	literals := placeholderRegx.Split(entry.Template, -1)
+
+	if len(literals) == 1 || !strings.HasPrefix(entry.Message, literals[0]) {
+		return palette.paint("m", entry.Message)
+	}
+
+	var buff strings.Builder
+
+	buff.WriteString(palette.paint("m", literals[0]))
+
+	rest := entry.Message[len(literals[0]):]
+
+	for i, literal := range literals[1:] {
+		var index int
+
+		switch {
+		case i == len(literals)-2 && literal == "":
+			index = len(rest)
+		case i == len(literals)-2:
+			index = strings.LastIndex(rest, literal)
+
+			if index != -1 && index+len(literal) != len(rest) {
+				index = -1
+			}
+		case literal == "":
+			// adjacent placeholders can't be separated
+			index = -1
+		default:
+			index = strings.Index(rest, literal)
+		}
+
+		if index == -1 {
+			return palette.paint("m", entry.Message)
+		}
+
+		buff.WriteString(palette.paint("v", rest[:index]))
+		buff.WriteString(palette.paint("m", literal))
+
+		rest = rest[index+len(literal):]
+	}
+
+	return buff.String()
+}
+
+func isStackTrace(value string) bool {
+	if !strings.Contains(value, "\n") {
+		return false
+	}
+
+	return strings.HasPrefix(value, "goroutine ") || (strings.Contains(value, "\n\t") && strings.Contains(value, ".go:"))
+}
+
+// foldStack keep the first stackLines lines of stack trace
+func (pretty *pretty) foldStack(value string) string {
+	if pretty.stackLines <= 0 || !isStackTrace(value) {
+		return value
+	}
+
+	lines := strings.Split(strings.TrimSuffix(value, "\n"), "\n")
+
+	if len(lines) <= pretty.stackLines {
+		return value
+	}
+
+	return strings.Join(lines[:pretty.stackLines], "\n") + fmt.Sprintf("\n... %d more lines", len(lines)-pretty.stackLines)
+}
+
+func (pretty *pretty) attrValue(value interface{}) interface{} {
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+
+	if s, ok := value.(string); ok {
+		return pretty.foldStack(s)
+	}
+
+	return value
+}
+
+func (pretty *pretty) renderKV(buff *strings.Builder, keys []string, attrs map[string]interface{}, palette *palette) {
+	width := 0
+
+	for _, key := range keys {
+		if len(attrName(key)) > width {
+			width = len(attrName(key))
+		}
+	}
+
+	for _, key := range keys {
+		name := attrName(key)
+		value := pretty.attrValue(attrs[key])
+
+		text, ok := value.(string)
+
+		if !ok {
+			text = slf4go.AttrString(value)
+		}
+
+		// continuation lines are aligned with the value column
+		text = strings.Replace(text, "\n", "\n"+prettyIndent+strings.Repeat(" ", width+3), -1)
+
+		buff.WriteString("\n")
+		buff.WriteString(prettyIndent)
+		buff.WriteString(palette.paint("k", name))
+		buff.WriteString(strings.Repeat(" ", width-len(name)))
+		buff.WriteString(palette.paint("", " = "))
+		buff.WriteString(palette.paint("a", text))
+	}
+}
+
+func (pretty *pretty) renderJSON(buff *strings.Builder, keys []string, attrs map[string]interface{}, palette *palette) {
+	buff.WriteString("\n")
+	buff.WriteString(prettyIndent)
+	buff.WriteString(palette.paint("", "{"))
+
+	for i, key := range keys {
+		name, _ := json.Marshal(attrName(key))
+
+		data, err := json.MarshalIndent(pretty.attrValue(attrs[key]), prettyIndent+"  ", "  ")
+
+		if err != nil {
+			data, _ = json.Marshal(slf4go.AttrString(attrs[key]))
+		}
+
+		buff.WriteString("\n")
+		buff.WriteString(prettyIndent + "  ")
+		buff.WriteString(palette.paint("k", string(name)))
+		buff.WriteString(palette.paint("", ": "))
+		buff.WriteString(palette.paint("a", string(data)))
+
+		if i < len(keys)-1 {
+			buff.WriteString(palette.paint("", ","))
+		}
+	}
+
+	buff.WriteString("\n")
+	buff.WriteString(prettyIndent)
+	buff.WriteString(palette.paint("", "}"))
+}
+
+func (pretty *pretty) render(entry *slf4go.EventEntry, palette *palette) string {
+	var buff strings.Builder
+
+	buff.WriteString(palette.paint("t", pretty.timeOf(entry)))
+	buff.WriteString(" ")
+	buff.WriteString(palette.paint("l", fmt.Sprintf("%-5s", strings.ToUpper(entry.Level.String()))))
+	buff.WriteString(" ")
+
+	if entry.Source != "" {
+		buff.WriteString(palette.paint("s", entry.Source))
+		buff.WriteString(" ")
+	}
+
+	buff.WriteString(pretty.highlightMessage(entry, palette))
+
+	if pretty.attrs == attrsNone || len(entry.Attrs) == 0 {
+		return buff.String()
+	}
+
+	keys := make([]string, 0, len(entry.Attrs))
+
+	for key := range entry.Attrs {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return attrName(keys[i]) < attrName(keys[j])
+	})
+
+	if pretty.attrs == attrsJSON {
+		pretty.renderJSON(&buff, keys, entry.Attrs, palette)
+	} else {
+		pretty.renderKV(&buff, keys, entry.Attrs, palette)
+	}
+
+	return buff.String()
+}
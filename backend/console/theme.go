@@ -0,0 +1,217 @@
+package console
+
+import (
+	"os"
+	"strings"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/scf4go"
+	"github.com/libs4go/slf4go"
+)
+
+// paint wrap text with color escape sequence, nil paint keeps text unchanged
+type paint func(text string) string
+
+func (p paint) apply(text string) string {
+	if p == nil || text == "" {
+		return text
+	}
+
+	return p(text)
+}
+
+// colorAttributes SGR codes of color names used in theme, fg colors may be prefixed with
+// 'hi_' for high intensity and 'bg_' for background
+var colorAttributes = map[string]int{
+	"bold":      1,
+	"faint":     2,
+	"italic":    3,
+	"underline": 4,
+	"black":     30,
+	"red":       31,
+	"green":     32,
+	"yellow":    33,
+	"blue":      34,
+	"magenta":   35,
+	"cyan":      36,
+	"white":     37,
+}
+
+// parseColor parse color spec like 'bold hi_red' or 'black,bg_yellow' into SGR codes
+func parseColor(spec string) ([]int, error) {
+	var attrs []int
+
+	for _, name := range strings.FieldsFunc(spec, func(r rune) bool { return r == ' ' || r == ',' }) {
+		name = strings.ToLower(name)
+
+		offset := 0
+
+		if strings.HasPrefix(name, "bg_") {
+			name = strings.TrimPrefix(name, "bg_")
+			offset += 10
+		}
+
+		if strings.HasPrefix(name, "hi_") {
+			name = strings.TrimPrefix(name, "hi_")
+			offset += 60
+		}
+
+		attr, ok := colorAttributes[name]
+
+		if !ok || (offset != 0 && attr < 30) {
+			return nil, errors.Wrap(slf4go.ErrArgs, "unknown color %s", spec)
+		}
+
+		attrs = append(attrs, attr+offset)
+	}
+
+	return attrs, nil
+}
+
+// defaultLevelColors the per-level colors used before themes were configurable
+var defaultLevelColors = map[slf4go.Level]string{
+	slf4go.TRACE: "blue",
+	slf4go.DEBUG: "cyan",
+	slf4go.INFO:  "white",
+	slf4go.WARN:  "yellow",
+	slf4go.ERROR: "red",
+}
+
+// themeTokens theme keys of layout tokens
+var themeTokens = map[string][]string{
+	"timestamp": {"t"},
+	"level":     {"l"},
+	"message":   {"m", "mt"},
+	"logger":    {"s"},
+	"caller":    {"f", "line", "func"},
+	"attr":      {"a"},
+	"key":       {"k"}, // attribute keys of pretty mode
+	"value":     {"v"}, // values substituted into template of pretty mode
+}
+
+// defaultTokenColors colors of theme keys not set in config
+var defaultTokenColors = map[string]string{
+	"value": "bold",
+}
+
+// theme colors of console output, line is painted with level color and tokens
+// with their own colors if any
+type theme struct {
+	levels map[slf4go.Level]paint
+	tokens map[string]paint
+}
+
+func newTheme(config scf4go.Config) (*theme, error) {
+	theme := defaultTheme()
+
+	for level := range defaultLevelColors {
+		spec := config.Get("theme", level.String()).String("")
+
+		if spec == "" {
+			continue
+		}
+
+		attrs, err := parseColor(spec)
+
+		if err != nil {
+			return nil, err
+		}
+
+		theme.levels[level] = newPaint(attrs)
+	}
+
+	for name, tokens := range themeTokens {
+		spec := config.Get("theme", name).String("")
+
+		if spec == "" {
+			continue
+		}
+
+		attrs, err := parseColor(spec)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, token := range tokens {
+			theme.tokens[token] = newPaint(attrs)
+		}
+	}
+
+	return theme, nil
+}
+
+func defaultTheme() *theme {
+	theme := &theme{
+		levels: make(map[slf4go.Level]paint),
+		tokens: make(map[string]paint),
+	}
+
+	for level, def := range defaultLevelColors {
+		attrs, _ := parseColor(def)
+		theme.levels[level] = newPaint(attrs)
+	}
+
+	for name, def := range defaultTokenColors {
+		attrs, _ := parseColor(def)
+
+		for _, token := range themeTokens[name] {
+			theme.tokens[token] = newPaint(attrs)
+		}
+	}
+
+	return theme
+}
+
+// palette colors of one entry, nil palette renders plain text
+type palette struct {
+	base   paint
+	tokens map[string]paint
+}
+
+func (theme *theme) palette(level slf4go.Level) *palette {
+	return &palette{
+		base:   theme.levels[level],
+		tokens: theme.tokens,
+	}
+}
+
+func (palette *palette) paint(token string, text string) string {
+	if palette == nil {
+		return text
+	}
+
+	if p, ok := palette.tokens[token]; ok {
+		return p.apply(text)
+	}
+
+	return palette.base.apply(text)
+}
+
+// color output modes
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// colorEnabled check if colored output is written into file, auto mode honors FORCE_COLOR and NO_COLOR
+// environment variables before checking the file is a terminal
+func colorEnabled(mode string, file *os.File) bool {
+	switch mode {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	}
+
+	if force := os.Getenv("FORCE_COLOR"); force != "" && force != "0" && force != "false" {
+		return true
+	}
+
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	return isTerminal(file)
+}
@@ -1,11 +1,13 @@
 package file
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/libs4go/errors"
@@ -25,15 +27,58 @@ func getSize(path string) int64 {
 	return fileSize
 }
 
+// fsync policies
+const (
+	fsyncNever    = "never"
+	fsyncSync     = "sync"     // fsync on Sync and Flush
+	fsyncInterval = "interval" // fsync every fsync_interval
+	fsyncAlways   = "always"   // fsync after every write
+)
+
 type filebackendImpl struct {
+	written            uint64        // accessed atomically, keep 64-bit aligned
+	failed             uint64        // accessed atomically, keep 64-bit aligned
 	Path               string        `json:"path"`
 	Name               string        `json:"name"`
 	Extension          string        `json:"extension"`
 	MaxSize            int64         `json:"maxsize"`
 	RotationTime       time.Duration `json:"rotation_time"`
+	RotationAligned    bool          `json:"rotation_aligned"`
+	Timezone           string        `json:"timezone"`
+	MaxLines           int64         `json:"max_lines"`
 	TimestampFormatter string        `json:"timestamp"`
+	BufferSize         int           `json:"buffer_size"`
+	FlushInterval      time.Duration `json:"flush_interval"` // write buffered lines periodically, 0 disables
+	Fsync              string        `json:"fsync"`
+	FsyncInterval      time.Duration `json:"fsync_interval"`
+	MultiProcess       bool          `json:"multiprocess"`
+	Pattern            string        `json:"pattern"`
+	Symlink            string        `json:"symlink"`
+	Fixed              bool          `json:"fixed"` // fixed file name rotated by external tools
+	FileMode           os.FileMode   `json:"file_mode"`
+	DirMode            os.FileMode   `json:"dir_mode"`
+	Header             bool          `json:"header"` // write header record at the start of each new file
+	App                string        `json:"app"`
+	Version            string        `json:"version"`
+	configHash         string
 	currentPath        string
 	currentTimestamp   time.Time
+	nextRotation       time.Time
+	location           *time.Location // time zone of file names and aligned rotation
+	backendName        string
+	formatter          slf4go.Formatter
+	mutex              sync.Mutex // guard all fields except counters
+	file               *os.File   // current file, nil if not opened yet or closed after error
+	buffer             []byte     // buffered whole lines
+	size               int64      // current file size including buffered bytes
+	lines              int64      // lines written into current file including buffered lines
+	dirty              bool       // written since last fsync
+	lock               *fileLock  // advisory lock shared by processes in multiprocess mode
+	retention          retention
+	naming             *naming
+	cleanup            chan struct{}     // trigger cleanup of rotated files
+	events             chan *RotateEvent // rotation events notified in background
+	stop               chan struct{}     // stop background loops
 }
 
 func new() *filebackendImpl {
@@ -44,138 +89,633 @@ func new() *filebackendImpl {
 		MaxSize:            1024 * 1024 * 10,
 		RotationTime:       time.Hour * 24,
 		TimestampFormatter: "2006-01-02T15:04:05Z07:00",
+		BufferSize:         64 * 1024,
+		Fsync:              fsyncSync,
+		FsyncInterval:      time.Second,
+		FlushInterval:      time.Second,
+		Pattern:            defaultPattern,
+		FileMode:           0600,
+		DirMode:            0755,
+		App:                defaultApp(),
+		formatter:          slf4go.CLEFFormatter,
+		location:           time.Local,
 	}
 
+	impl.naming, _ = newNaming(impl.Path, impl.Pattern, impl.Name, impl.Extension, impl.TimestampFormatter, impl.location)
+
 	impl.newFilePath()
 
 	return impl
 }
 
 func (filebackend *filebackendImpl) Send(entry *slf4go.EventEntry) {
-	buff, err := json.Marshal(entry)
+	filebackend.mutex.Lock()
+	defer filebackend.mutex.Unlock()
+
+	buff, err := filebackend.formatter.Format(entry)
 	if err != nil {
-		println("marshal event entry error")
+		println(fmt.Sprintf("format event entry error %s", err))
+		atomic.AddUint64(&filebackend.failed, 1)
 		return
 	}
 
-	file, err := os.OpenFile(filebackend.currentPath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	// only whole lines are written, so lines of processes sharing the file never interleave
+	if len(filebackend.buffer) > 0 && len(filebackend.buffer)+len(buff)+1 > filebackend.BufferSize {
+		if err := filebackend.flushBuffer(); err != nil {
+			println(fmt.Sprintf("write to file %s error %s", filebackend.currentPath, err))
+			atomic.AddUint64(&filebackend.failed, 1)
+		}
+	}
 
-	if err != nil {
-		println(fmt.Sprintf("open file %s error %s", filebackend.currentPath, err))
+	filebackend.buffer = append(filebackend.buffer, buff...)
+	filebackend.buffer = append(filebackend.buffer, '\n')
+	filebackend.lines++
+
+	if filebackend.Fsync == fsyncAlways {
+		if err := filebackend.syncFile(); err != nil {
+			println(fmt.Sprintf("sync file %s error %s", filebackend.currentPath, err))
+			atomic.AddUint64(&filebackend.failed, 1)
+			return
+		}
+	}
+
+	// rotation of multiprocess mode is checked when the buffer is written with lock held
+	if filebackend.MultiProcess {
 		return
 	}
 
-	defer file.Close()
+	if reason := filebackend.rotationReason(); reason != "" {
+		filebackend.rotate(reason)
+	}
+}
+
+// open current file if it is not opened
+func (filebackend *filebackendImpl) open() error {
+	if filebackend.file != nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filebackend.currentPath), filebackend.DirMode); err != nil {
+		return err
+	}
 
-	_, err = file.WriteString(fmt.Sprintf("%s\n", string(buff)))
+	file, err := os.OpenFile(filebackend.currentPath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, filebackend.FileMode)
 
 	if err != nil {
-		println(fmt.Sprintf("write to file %s error %s", filebackend.currentPath, err))
-		return
+		return err
 	}
 
 	info, err := file.Stat()
 
 	if err != nil {
-		println(fmt.Sprintf("get file info %s error %s", filebackend.currentPath, err))
-		return
+		file.Close()
+		return err
 	}
 
-	if info.Size() > filebackend.MaxSize {
-		filebackend.newFilePath()
-		return
+	filebackend.file = file
+	filebackend.size = info.Size()
+
+	if filebackend.Header && filebackend.size == 0 {
+		if err := filebackend.writeHeader(); err != nil {
+			filebackend.closeHandle()
+			return err
+		}
+	}
+
+	if filebackend.Symlink != "" {
+		symlink := filepath.Join(filebackend.Path, filebackend.Symlink)
+
+		if err := updateSymlink(symlink, filebackend.currentPath); err != nil {
+			println(fmt.Sprintf("update symlink %s error %s", symlink, err))
+		}
+	}
+
+	return nil
+}
+
+// flushBuffer write buffered lines into current file, lines are dropped if the write fails
+// and the file is reopened by next write
+func (filebackend *filebackendImpl) flushBuffer() error {
+	if len(filebackend.buffer) == 0 {
+		return nil
+	}
+
+	defer func() {
+		filebackend.buffer = filebackend.buffer[:0]
+	}()
+
+	if filebackend.MultiProcess {
+		return filebackend.flushShared()
+	}
+
+	return filebackend.write()
+}
+
+func (filebackend *filebackendImpl) write() error {
+	if filebackend.Fixed {
+		filebackend.checkReplaced()
+	}
+
+	if err := filebackend.open(); err != nil {
+		return err
+	}
+
+	n, err := filebackend.file.Write(filebackend.buffer)
+
+	atomic.AddUint64(&filebackend.written, uint64(n))
+	filebackend.size += int64(n)
+	filebackend.dirty = true
+
+	if err != nil {
+		filebackend.closeHandle()
+		return err
+	}
+
+	return nil
+}
+
+// flushShared write buffered lines with the advisory lock held, the current file name is shared
+// through the lock file so the first process exceeding the limits rotates for all processes
+func (filebackend *filebackendImpl) flushShared() error {
+	if err := filebackend.lock.Lock(); err != nil {
+		return errors.Wrap(err, "lock %s error", filebackend.lock.path)
+	}
+
+	defer filebackend.lock.Unlock()
+
+	name, err := filebackend.lock.Read()
+
+	if err != nil {
+		return errors.Wrap(err, "read %s error", filebackend.lock.path)
+	}
+
+	if name != "" && name != filebackend.relativePath() {
+		// rotated by other process
+		filebackend.closeHandle()
+		filebackend.currentPath = filepath.Join(filebackend.Path, name)
+		filebackend.currentTimestamp = filebackend.timestampOf(filebackend.currentPath)
+		filebackend.nextRotation = filebackend.rotationAt(filebackend.currentTimestamp)
+
+		if filebackend.MaxLines > 0 {
+			filebackend.lines = countLines(filebackend.currentPath) + int64(bytes.Count(filebackend.buffer, []byte("\n")))
+		}
+	}
+
+	if err := filebackend.open(); err != nil {
+		return err
+	}
+
+	// other processes append to the file too
+	if info, err := filebackend.file.Stat(); err == nil {
+		filebackend.size = info.Size()
 	}
 
-	if filebackend.currentTimestamp.Add(filebackend.RotationTime).Unix() < time.Now().Unix() {
-		println(filebackend.currentTimestamp.Add(filebackend.RotationTime).Unix(), time.Now().Unix())
+	if reason := filebackend.rotationReason(); filebackend.size > 0 && reason != "" {
+		previous := filebackend.currentPath
+		filebackend.closeHandle()
 		filebackend.newFilePath()
-		return
+		filebackend.lines = int64(bytes.Count(filebackend.buffer, []byte("\n")))
+		filebackend.rotated(reason, previous)
+	}
+
+	if name != filebackend.relativePath() {
+		if err := filebackend.lock.Write(filebackend.relativePath()); err != nil {
+			return errors.Wrap(err, "write %s error", filebackend.lock.path)
+		}
+	}
+
+	return filebackend.write()
+}
+
+// relativePath current file path relative to backend directory, shared through the lock file
+func (filebackend *filebackendImpl) relativePath() string {
+	rel, err := filepath.Rel(filebackend.Path, filebackend.currentPath)
+
+	if err != nil {
+		return filepath.Base(filebackend.currentPath)
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+// timestampOf parse rotation timestamp of file path, now is returned if the path has no timestamp
+func (filebackend *filebackendImpl) timestampOf(path string) time.Time {
+	key, _, ok := filebackend.naming.parse(path)
+
+	if !ok || key.timestamp.IsZero() {
+		return time.Now()
 	}
+
+	return key.timestamp
+}
+
+// syncFile write buffered lines and fsync the file unless the policy is never
+func (filebackend *filebackendImpl) syncFile() error {
+	if err := filebackend.flushBuffer(); err != nil {
+		return err
+	}
+
+	if filebackend.file == nil || filebackend.Fsync == fsyncNever || !filebackend.dirty {
+		return nil
+	}
+
+	filebackend.dirty = false
+
+	return filebackend.file.Sync()
+}
+
+// closeHandle close current file without writing buffered lines
+func (filebackend *filebackendImpl) closeHandle() error {
+	if filebackend.file == nil {
+		return nil
+	}
+
+	var err error
+
+	if filebackend.Fsync != fsyncNever && filebackend.dirty {
+		err = filebackend.file.Sync()
+	}
+
+	if closeErr := filebackend.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	filebackend.file = nil
+	filebackend.dirty = false
+
+	return err
+}
+
+// closeFile write buffered lines and close current file, the file is reopened by next write
+func (filebackend *filebackendImpl) closeFile() error {
+	err := filebackend.flushBuffer()
+
+	if closeErr := filebackend.closeHandle(); err == nil {
+		err = closeErr
+	}
+
+	return err
 }
 
 func (filebackend *filebackendImpl) Sync() {
+	if err := filebackend.Flush(context.Background()); err != nil {
+		println(err.Error())
+	}
+}
+
+// Flush write buffered entries into file
+func (filebackend *filebackendImpl) Flush(ctx context.Context) error {
+	filebackend.mutex.Lock()
+	defer filebackend.mutex.Unlock()
+
+	if err := filebackend.syncFile(); err != nil {
+		atomic.AddUint64(&filebackend.failed, 1)
+		filebackend.closeHandle()
+
+		return errors.Wrap(err, "sync file %s error", filebackend.currentPath)
+	}
 
+	return nil
 }
 
-func (filebackend *filebackendImpl) Config(config scf4go.Config) error {
+// Close flush and close current file and stop background loops
+func (filebackend *filebackendImpl) Close(ctx context.Context) error {
+	filebackend.mutex.Lock()
+	defer filebackend.mutex.Unlock()
 
-	filebackend.Path = config.Get("path").String("./")
-	filebackend.Name = config.Get("name").String("unknown")
-	filebackend.Extension = config.Get("extension").String("log")
-	filebackend.MaxSize = int64(config.Get("maxsize").Int(1024 * 1024 * 10))
-	filebackend.RotationTime = config.Get("rotation_time").Duration(time.Hour * 24)
+	filebackend.stopLoop()
+
+	unregister(filebackend)
+
+	err := filebackend.closeFile()
+
+	if filebackend.lock != nil {
+		filebackend.lock.Close()
+		filebackend.lock = nil
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "close file %s error", filebackend.currentPath)
+	}
 
-	return filebackend.checkConfig()
+	return nil
 }
 
-func (filebackend *filebackendImpl) checkConfig() error {
-	err := os.MkdirAll(filebackend.Path, 0755)
+func (filebackend *filebackendImpl) fsyncLoop(stop chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		filebackend.mutex.Lock()
+
+		if err := filebackend.syncFile(); err != nil {
+			println(fmt.Sprintf("sync file %s error %s", filebackend.currentPath, err))
+			atomic.AddUint64(&filebackend.failed, 1)
+			filebackend.closeHandle()
+		}
+
+		filebackend.mutex.Unlock()
+	}
+}
+
+// flushLoop write buffered lines periodically whatever the fsync policy is, so lines of quiet
+// services are visible to readers and not lost on crash
+func (filebackend *filebackendImpl) flushLoop(stop chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		filebackend.mutex.Lock()
+
+		if err := filebackend.flushBuffer(); err != nil {
+			println(fmt.Sprintf("write to file %s error %s", filebackend.currentPath, err))
+			atomic.AddUint64(&filebackend.failed, 1)
+		}
+
+		filebackend.mutex.Unlock()
+	}
+}
+
+// stopLoop stop background loops, must be called with mutex held
+func (filebackend *filebackendImpl) stopLoop() {
+	if filebackend.stop != nil {
+		close(filebackend.stop)
+		filebackend.stop = nil
+		filebackend.cleanup = nil
+		filebackend.events = nil
+	}
+}
+
+func (filebackend *filebackendImpl) Stats() slf4go.BackendStats {
+	return slf4go.BackendStats{
+		Written: atomic.LoadUint64(&filebackend.written),
+		Failed:  atomic.LoadUint64(&filebackend.failed),
+	}
+}
+
+func (filebackend *filebackendImpl) Config(config scf4go.Config) error {
+
+	policy := config.Get("fsync").String(fsyncSync)
+	interval := config.Get("fsync_interval").Duration(time.Second)
+
+	switch policy {
+	case fsyncNever, fsyncSync, fsyncAlways:
+	case fsyncInterval:
+		if interval <= 0 {
+			return errors.Wrap(slf4go.ErrArgs, "invalid fsync_interval %s", interval)
+		}
+	default:
+		return errors.Wrap(slf4go.ErrArgs, "unknown fsync policy %s", policy)
+	}
+
+	flushInterval := config.Get("flush_interval").Duration(time.Second)
+
+	if flushInterval < 0 {
+		return errors.Wrap(slf4go.ErrArgs, "invalid flush_interval %s", flushInterval)
+	}
+
+	formatter, err := slf4go.NewFormatter(config, "clef")
 
 	if err != nil {
-		return errors.Wrap(err, "create dir %s error", filebackend.Path)
+		return err
+	}
+
+	fileMode, err := parseMode(config.Get("file_mode").String("0600"))
+
+	if err != nil {
+		return err
+	}
+
+	dirMode, err := parseMode(config.Get("dir_mode").String("0755"))
+
+	if err != nil {
+		return err
+	}
+
+	maxSize, err := slf4go.ConfigSize(config.Get("maxsize"), 1024*1024*10)
+
+	if err != nil {
+		return err
+	}
+
+	maxTotalSize, err := slf4go.ConfigSize(config.Get("max_total_size"), 0)
+
+	if err != nil {
+		return err
+	}
+
+	bufferSize, err := slf4go.ConfigSize(config.Get("buffer_size"), 64*1024)
+
+	if err != nil {
+		return err
 	}
 
-	var lastFileTimestamp *time.Time
-	var lastFilePath string
+	path := config.Get("path").String("./")
+	name := config.Get("name").String("unknown")
+	extension := config.Get("extension").String("log")
+	timestamp := config.Get("timestamp").String("2006-01-02T15:04:05Z07:00")
+	fixed := config.Get("fixed").Bool(false)
 
-	filepath.Walk(filebackend.Path, func(path string, info os.FileInfo, err error) error {
+	pattern := config.Get("pattern").String(defaultPattern)
 
-		fileName := filepath.Base(path)
+	if fixed {
+		pattern = config.Get("pattern").String(fixedPattern)
+	}
+
+	timezone := config.Get("timezone").String("")
 
-		fileName = strings.TrimSuffix(fileName, "."+strings.TrimPrefix(filebackend.Extension, "."))
+	location, err := time.LoadLocation(timezone)
+
+	if err != nil {
+		return errors.Wrap(slf4go.ErrArgs, "unknown timezone %s", timezone)
+	}
 
-		if strings.HasPrefix(fileName, filebackend.Name+"-") {
+	naming, err := newNaming(path, pattern, name, extension, timestamp, location)
 
-			suffix := strings.TrimPrefix(fileName, filebackend.Name+"-")
+	if err != nil {
+		return err
+	}
 
-			timestamp, err := time.Parse(filebackend.TimestampFormatter, suffix)
+	filebackend.mutex.Lock()
+	defer filebackend.mutex.Unlock()
 
-			if err != nil {
-				println(fmt.Sprintf("parse %s timestamp error skipped: %s", path, err))
-				return nil
-			}
+	filebackend.stopLoop()
 
-			if lastFileTimestamp != nil && lastFileTimestamp.After(timestamp) {
-				return nil
-			}
+	if err := filebackend.closeFile(); err != nil {
+		println(fmt.Sprintf("close file %s error %s", filebackend.currentPath, err))
+	}
+
+	if filebackend.lock != nil {
+		filebackend.lock.Close()
+		filebackend.lock = nil
+	}
+
+	filebackend.Path = path
+	filebackend.Name = name
+	filebackend.Extension = extension
+	filebackend.TimestampFormatter = timestamp
+	filebackend.Pattern = pattern
+	filebackend.Symlink = config.Get("symlink").String("")
+	filebackend.naming = naming
+	filebackend.MaxSize = maxSize
+	filebackend.RotationTime = config.Get("rotation_time").Duration(time.Hour * 24)
+	filebackend.RotationAligned = config.Get("rotation_aligned").Bool(false)
+	filebackend.Timezone = timezone
+	filebackend.location = location
+	filebackend.MaxLines = int64(config.Get("max_lines").Int(0))
+	filebackend.BufferSize = int(bufferSize)
+	filebackend.FlushInterval = flushInterval
+	filebackend.Fsync = policy
+	filebackend.FsyncInterval = interval
+	filebackend.MultiProcess = config.Get("multiprocess").Bool(false)
+	filebackend.Fixed = fixed
+	filebackend.FileMode = fileMode
+	filebackend.DirMode = dirMode
+	filebackend.Header = config.Get("header").Bool(false)
+	filebackend.App = config.Get("app").String(defaultApp())
+	filebackend.Version = config.Get("version").String("")
+	filebackend.configHash = configHash(config)
+	filebackend.formatter = formatter
+	filebackend.retention = retention{
+		maxFiles:     config.Get("max_files").Int(0),
+		maxAge:       config.Get("max_age").Duration(0),
+		maxTotalSize: maxTotalSize,
+		compress:     config.Get("compress").Bool(false),
+	}
+
+	if err := filebackend.checkConfig(); err != nil {
+		return err
+	}
 
-			if info.Size() > filebackend.MaxSize {
-				return nil
-			}
+	if filebackend.MultiProcess {
+		lock, err := openLock(filepath.Join(filebackend.Path, "."+filebackend.Name+".lock"), filebackend.FileMode)
 
-			lastFilePath = path
-			lastFileTimestamp = &timestamp
+		if err != nil {
+			return err
 		}
 
+		filebackend.lock = lock
+	}
+
+	filebackend.stop = make(chan struct{})
+
+	if filebackend.FlushInterval > 0 {
+		go filebackend.flushLoop(filebackend.stop, filebackend.FlushInterval)
+	}
+
+	if filebackend.Fsync == fsyncInterval {
+		go filebackend.fsyncLoop(filebackend.stop, filebackend.FsyncInterval)
+	}
+
+	if !filebackend.Fixed && filebackend.RotationTime > 0 {
+		go filebackend.rotationLoop(filebackend.stop)
+	}
+
+	filebackend.cleanup = make(chan struct{}, 1)
+	filebackend.events = make(chan *RotateEvent, 64)
+
+	go filebackend.backgroundLoop(filebackend.events, filebackend.cleanup, filebackend.stop)
+
+	if filebackend.retention.enabled() {
+		// clean files left by previous runs
+		filebackend.triggerCleanup()
+	}
+
+	register(filebackend)
+
+	return nil
+}
+
+func (filebackend *filebackendImpl) checkConfig() error {
+	err := os.MkdirAll(filebackend.Path, filebackend.DirMode)
+
+	if err != nil {
+		return errors.Wrap(err, "create dir %s error", filebackend.Path)
+	}
+
+	if filebackend.Fixed {
+		filebackend.currentTimestamp = time.Now().In(filebackend.location)
+		filebackend.currentPath = filebackend.naming.file(filebackend.currentTimestamp, 0)
+		filebackend.nextRotation = filebackend.rotationAt(filebackend.currentTimestamp)
+
 		return nil
-	})
+	}
+
+	var last *logFile
 
-	if lastFileTimestamp == nil {
+	files, err := filebackend.naming.list()
+
+	if err != nil {
+		return errors.Wrap(err, "list files of %s error", filebackend.Path)
+	}
+
+	for _, file := range files {
+		// archives are never appended
+		if file.compressed || file.size > filebackend.MaxSize {
+			continue
+		}
+
+		if last == nil || !file.key.before(last.key) {
+			last = file
+		}
+	}
+
+	if last == nil {
 		filebackend.newFilePath()
 		return nil
 	}
 
-	if lastFileTimestamp.Add(filebackend.RotationTime).Unix() < time.Now().Unix() {
+	timestamp := last.key.timestamp
+
+	if timestamp.IsZero() {
+		// the pattern has no time token
+		timestamp = last.modTime
+	}
+
+	if !time.Now().Before(filebackend.rotationAt(timestamp)) {
 		filebackend.newFilePath()
 		return nil
 	}
 
-	filebackend.currentPath = lastFilePath
-	filebackend.currentTimestamp = *lastFileTimestamp
+	filebackend.currentPath = last.path
+	filebackend.currentTimestamp = timestamp
+	filebackend.nextRotation = filebackend.rotationAt(timestamp)
+	filebackend.size = last.size
+	filebackend.lines = 0
+
+	if filebackend.MaxLines > 0 {
+		filebackend.lines = countLines(last.path)
+	}
 
 	return nil
 }
 
+// newFilePath switch to the path of new file, the file is created by next write
 func (filebackend *filebackendImpl) newFilePath() {
-	filebackend.currentTimestamp = time.Now()
-	fileName := fmt.Sprintf("%s-%s.%s",
-		filebackend.Name,
-		filebackend.currentTimestamp.Format(filebackend.TimestampFormatter),
-		filebackend.Extension)
-
-	filebackend.currentPath = filepath.Join(filebackend.Path, fileName)
+	filebackend.currentTimestamp = time.Now().In(filebackend.location)
+	filebackend.currentPath = filebackend.naming.next(filebackend.currentTimestamp)
+	filebackend.nextRotation = filebackend.rotationAt(filebackend.currentTimestamp)
+	filebackend.size = 0
+	filebackend.lines = 0
 }
 
 func init() {
-	slf4go.RegisterBackend("file", new())
+	slf4go.RegisterBackendType("file", func(name string) slf4go.Backend {
+		impl := new()
+		impl.backendName = name
+
+		return impl
+	})
 }
@@ -1,12 +1,24 @@
 package file
 
 import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/libs4go/scf4go"
 	_ "github.com/libs4go/scf4go/codec" //
 	"github.com/libs4go/scf4go/reader/file"
 	"github.com/libs4go/slf4go"
+	"github.com/libs4go/slf4go/slf4gotest"
 	"github.com/stretchr/testify/require"
 )
 
@@ -22,3 +34,555 @@ func TestLog(t *testing.T) {
 
 	slf4go.Get("test").D("test a {@test}", 1)
 }
+
+func readLogs(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	require.NoError(t, err)
+
+	sort.Strings(files)
+
+	var lines []string
+
+	for _, path := range files {
+		buff, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+
+		for _, line := range strings.Split(strings.TrimSuffix(string(buff), "\n"), "\n") {
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+
+	return lines
+}
+
+func TestBuffered(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slf4go")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	backend := new()
+
+	err = backend.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+path: %s
+name: test
+maxsize: 100000
+fsync: always
+format: text
+template: "{{.Message}}"
+`, dir)))
+
+	require.NoError(t, err)
+
+	backend.Send(&slf4go.EventEntry{Message: "hello"})
+
+	require.Equal(t, []string{"hello"}, readLogs(t, dir))
+
+	err = backend.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+path: %s
+name: test
+maxsize: 100000
+fsync: sync
+flush_interval: 0
+format: text
+template: "{{.Message}}"
+`, dir)))
+
+	require.NoError(t, err)
+
+	backend.Send(&slf4go.EventEntry{Message: "world"})
+
+	// buffered until sync
+	require.Equal(t, []string{"hello"}, readLogs(t, dir))
+
+	backend.Sync()
+
+	require.Equal(t, []string{"hello", "world"}, readLogs(t, dir))
+
+	// the file is reopened after close
+	require.NoError(t, backend.Close(context.Background()))
+
+	backend.Send(&slf4go.EventEntry{Message: "again"})
+
+	require.NoError(t, backend.Flush(context.Background()))
+
+	require.Equal(t, []string{"hello", "world", "again"}, readLogs(t, dir))
+
+	require.Equal(t, uint64(len("hello\nworld\nagain\n")), backend.Stats().Written)
+
+	require.Error(t, backend.Config(slf4gotest.LoadConfig(t, "fsync: sometimes")))
+
+	// buffered lines are written periodically without Sync
+	err = backend.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+path: %s
+name: test
+maxsize: 100000
+flush_interval: 50ms
+format: text
+template: "{{.Message}}"
+`, dir)))
+
+	require.NoError(t, err)
+
+	defer backend.Close(context.Background())
+
+	backend.Send(&slf4go.EventEntry{Message: "periodic"})
+
+	for i := 0; ; i++ {
+		if lines := readLogs(t, dir); lines[len(lines)-1] == "periodic" {
+			break
+		}
+
+		require.True(t, i < 100, "buffered line not written")
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slf4go")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	config := slf4gotest.LoadConfig(t, fmt.Sprintf(`
+path: %s
+name: test
+buffer_size: 256
+multiprocess: true
+format: text
+template: "{{.Message}}"
+`, dir))
+
+	// backends sharing the directory stand for processes, each one has its own lock file handle
+	backends := []*filebackendImpl{new(), new()}
+
+	for _, backend := range backends {
+		require.NoError(t, backend.Config(config))
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			backend := backends[i%len(backends)]
+
+			for j := 0; j < 100; j++ {
+				backend.Send(&slf4go.EventEntry{Message: fmt.Sprintf("writer %d line %03d %s", i, j, strings.Repeat("x", 40))})
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	for _, backend := range backends {
+		require.NoError(t, backend.Close(context.Background()))
+	}
+
+	lines := readLogs(t, dir)
+
+	require.Equal(t, 800, len(lines))
+
+	for _, line := range lines {
+		require.Regexp(t, `^writer \d line \d{3} x{40}$`, line)
+	}
+}
+
+func TestRetention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slf4go")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	backend := new()
+
+	now := time.Now()
+
+	var paths []string
+
+	for i := 5; i > 0; i-- {
+		path := filepath.Join(dir, fmt.Sprintf("test-%s.log", now.Add(-time.Duration(i)*time.Hour).Format(backend.TimestampFormatter)))
+		require.NoError(t, ioutil.WriteFile(path, []byte(fmt.Sprintf("old %d\n", i)), 0600))
+
+		paths = append(paths, path)
+	}
+
+	require.NoError(t, os.Chtimes(paths[0], now.Add(-48*time.Hour), now.Add(-48*time.Hour)))
+
+	err = backend.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+path: %s
+name: test
+pattern: "{name}-{timestamp}.{ext}"
+rotation_time: 30m
+max_age: 24h
+max_files: 2
+compress: true
+`, dir)))
+
+	require.NoError(t, err)
+
+	defer backend.Close(context.Background())
+
+	expected := []string{filepath.Base(paths[3]) + ".gz", filepath.Base(paths[4]) + ".gz"}
+
+	list := func() []string {
+		files, err := ioutil.ReadDir(dir)
+		require.NoError(t, err)
+
+		var names []string
+
+		for _, file := range files {
+			names = append(names, file.Name())
+		}
+
+		return names
+	}
+
+	require.Eventually(t, func() bool {
+		return fmt.Sprint(list()) == fmt.Sprint(expected)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	file, err := os.Open(paths[4] + ".gz")
+	require.NoError(t, err)
+
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	require.NoError(t, err)
+
+	buff, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, "old 1\n", string(buff))
+
+	// archives are never resumed
+	err = backend.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+path: %s
+name: test
+pattern: "{name}-{timestamp}.{ext}"
+rotation_time: 24h
+`, dir)))
+
+	require.NoError(t, err)
+	require.Equal(t, ".log", filepath.Ext(backend.currentPath))
+	require.True(t, backend.currentTimestamp.After(now.Add(-time.Minute)))
+}
+
+func TestNaming(t *testing.T) {
+	naming, err := newNaming("/var/log", "%Y/%m-%d/{name}-%H%M%S-{index}.{ext}", "app", ".log", "", time.Local)
+	require.NoError(t, err)
+
+	timestamp := time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local)
+
+	path := naming.file(timestamp, 2)
+	require.Equal(t, filepath.FromSlash("/var/log/2020/01-02/app-030405-2.log"), path)
+
+	key, compressed, ok := naming.parse(path + ".gz")
+	require.True(t, ok)
+	require.True(t, compressed)
+	require.True(t, key.timestamp.Equal(timestamp))
+	require.Equal(t, 2, key.index)
+
+	_, _, ok = naming.parse(filepath.FromSlash("/var/log/2020/01-02/other-030405-2.log"))
+	require.False(t, ok)
+
+	naming, err = newNaming("/var/log", "{name}-{pid}.{ext}", "app", "log", "", time.Local)
+	require.NoError(t, err)
+
+	require.Equal(t, filepath.FromSlash(fmt.Sprintf("/var/log/app-%d.log.3", os.Getpid())), naming.file(timestamp, 3))
+
+	key, _, ok = naming.parse(filepath.FromSlash("/var/log/app-1.log.3"))
+	require.True(t, ok)
+	require.Equal(t, 3, key.index)
+
+	_, err = newNaming("/var/log", "{name}-%Q.{ext}", "app", "log", "", time.Local)
+	require.Error(t, err)
+
+	dir, err := ioutil.TempDir("", "slf4go")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	backend := new()
+
+	err = backend.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+path: %s
+name: test
+maxsize: 10
+pattern: "%%Y%%m%%d/{name}-{host}-%%H%%M%%S-{index}.{ext}"
+symlink: current.log
+format: text
+template: "{{.Message}}"
+`, dir)))
+
+	require.NoError(t, err)
+
+	// rotations within the same second get increasing index
+	for i := 0; i < 3; i++ {
+		backend.Send(&slf4go.EventEntry{Message: fmt.Sprintf("rotation %d", i)})
+		require.NoError(t, backend.Flush(context.Background()))
+	}
+
+	backend.Send(&slf4go.EventEntry{Message: "current"})
+	require.NoError(t, backend.Close(context.Background()))
+
+	files, err := backend.naming.list()
+	require.NoError(t, err)
+	require.Equal(t, 4, len(files))
+
+	for i, file := range files {
+		rel, err := filepath.Rel(dir, file.path)
+		require.NoError(t, err)
+		require.Equal(t, 1, strings.Count(filepath.ToSlash(rel), "/"))
+
+		if i > 0 {
+			require.True(t, files[i-1].key.before(file.key))
+		}
+	}
+
+	buff, err := ioutil.ReadFile(filepath.Join(dir, "current.log"))
+	require.NoError(t, err)
+	require.Equal(t, "current\n", string(buff))
+}
+
+func TestRotation(t *testing.T) {
+	backend := new()
+	backend.RotationTime = 6 * time.Hour
+	backend.RotationAligned = true
+	backend.location = time.FixedZone("UTC+8", 8*3600)
+
+	start := time.Date(2020, 1, 2, 13, 30, 0, 0, backend.location)
+	require.True(t, backend.rotationAt(start).Equal(time.Date(2020, 1, 2, 18, 0, 0, 0, backend.location)))
+	require.True(t, backend.rotationAt(start.UTC()).Equal(time.Date(2020, 1, 2, 18, 0, 0, 0, backend.location)))
+
+	backend.RotationTime = 48 * time.Hour
+	require.True(t, backend.rotationAt(start).Equal(time.Date(2020, 1, 4, 0, 0, 0, 0, backend.location)))
+
+	backend.RotationAligned = false
+	require.True(t, backend.rotationAt(start).Equal(start.Add(48*time.Hour)))
+
+	dir, err := ioutil.TempDir("", "slf4go")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	events := make(chan *RotateEvent, 16)
+
+	OnRotate(func(event *RotateEvent) {
+		if event.Backend == "rotation" {
+			events <- event
+		}
+	})
+
+	backend = new()
+	backend.backendName = "rotation"
+
+	err = backend.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+path: %s
+name: test
+max_lines: 3
+rotation_time: 200ms
+`, dir)))
+
+	require.NoError(t, err)
+
+	defer backend.Close(context.Background())
+
+	for i := 0; i < 7; i++ {
+		backend.Send(&slf4go.EventEntry{Source: "test", Message: fmt.Sprintf("line %d", i), Level: slf4go.INFO})
+	}
+
+	for i := 0; i < 2; i++ {
+		event := <-events
+		require.Equal(t, RotateLines, event.Reason)
+		require.NotEqual(t, event.Previous, event.Current)
+	}
+
+	// the last line is rotated by the timer without further writes
+	select {
+	case event := <-events:
+		require.Equal(t, RotateTime, event.Reason)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "rotation timer not fired")
+	}
+
+	require.Len(t, readLogs(t, dir), 7)
+}
+
+func TestSizeRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slf4go")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	backend := new()
+
+	err = backend.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+path: %s
+name: test
+maxsize: 1000
+format: text
+template: "{{.Message}}"
+`, dir)))
+
+	require.NoError(t, err)
+
+	// 100 bytes per line, the line exceeding maxsize is the last line of the file
+	for i := 0; i < 40; i++ {
+		backend.Send(&slf4go.EventEntry{Message: fmt.Sprintf("%-99d", i)})
+	}
+
+	require.NoError(t, backend.Close(context.Background()))
+
+	files, err := backend.naming.list()
+	require.NoError(t, err)
+
+	var counts []int
+
+	for _, file := range files {
+		buff, err := ioutil.ReadFile(file.path)
+		require.NoError(t, err)
+
+		counts = append(counts, strings.Count(string(buff), "\n"))
+	}
+
+	require.Equal(t, []int{11, 11, 11, 7}, counts)
+}
+
+func TestFixed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slf4go")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	backend := new()
+
+	err = backend.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+path: %s
+name: test
+fixed: true
+maxsize: 10
+format: text
+template: "{{.Message}}"
+`, dir)))
+
+	require.NoError(t, err)
+
+	defer backend.Close(context.Background())
+
+	path := filepath.Join(dir, "test.log")
+
+	send := func(message string) {
+		backend.Send(&slf4go.EventEntry{Source: "test", Message: message, Level: slf4go.INFO})
+	}
+
+	read := func(path string) string {
+		buff, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+
+		return string(buff)
+	}
+
+	send("first line exceeding max size")
+	require.NoError(t, backend.Flush(context.Background()))
+	require.Equal(t, path, backend.currentPath)
+
+	// moved by logrotate without signal
+	require.NoError(t, os.Rename(path, path+".1"))
+
+	send("second")
+	require.NoError(t, backend.Flush(context.Background()))
+	require.Equal(t, "first line exceeding max size\n", read(path+".1"))
+	require.Equal(t, "second\n", read(path))
+
+	// copytruncate
+	require.NoError(t, os.Truncate(path, 0))
+
+	send("third")
+	require.NoError(t, backend.Flush(context.Background()))
+	require.Equal(t, "third\n", read(path))
+
+	// buffered lines are kept across reopen
+	send("fourth")
+	require.NoError(t, os.Rename(path, path+".2"))
+	require.NoError(t, Reopen())
+	require.Equal(t, "fourth\n", read(path))
+
+	send("fifth")
+	require.NoError(t, backend.Flush(context.Background()))
+	require.Equal(t, "third\n", read(path+".2"))
+	require.Equal(t, "fourth\nfifth\n", read(path))
+}
+
+func TestHeader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slf4go")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	backend := new()
+
+	err = backend.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+path: %s/logs
+name: test
+maxsize: 1KB
+buffer_size: 0.5kb
+file_mode: "0640"
+dir_mode: "0750"
+header: true
+app: server
+version: 1.2.3
+`, dir)))
+
+	require.NoError(t, err)
+
+	defer backend.Close(context.Background())
+
+	require.Equal(t, int64(1024), backend.MaxSize)
+	require.Equal(t, 512, backend.BufferSize)
+
+	for i := 0; i < 20; i++ {
+		backend.Send(&slf4go.EventEntry{Source: "test", Message: fmt.Sprintf("message %d", i), Level: slf4go.INFO})
+	}
+
+	require.NoError(t, backend.Flush(context.Background()))
+
+	files, err := filepath.Glob(filepath.Join(dir, "logs", "*.log"))
+	require.NoError(t, err)
+	require.True(t, len(files) > 1)
+
+	for _, path := range files {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.Zero(t, info.Mode().Perm()&^0640)
+
+		buff, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+
+		var header map[string]interface{}
+
+		require.NoError(t, json.Unmarshal([]byte(strings.SplitN(string(buff), "\n", 2)[0]), &header))
+		require.Equal(t, headerMessage, header["@m"])
+
+		// default CLEF output keeps attributes as top-level properties
+		require.Equal(t, "server", header["app"])
+		require.Equal(t, "1.2.3", header["version"])
+		require.Equal(t, float64(os.Getpid()), header["pid"])
+		require.Equal(t, backend.configHash, header["config_hash"])
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "logs"))
+	require.NoError(t, err)
+	require.Zero(t, info.Mode().Perm()&^0750)
+
+	err = backend.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+path: %s
+file_mode: rw
+`, dir)))
+
+	require.Error(t, err)
+}
@@ -0,0 +1,84 @@
+package file
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/scf4go"
+	"github.com/libs4go/slf4go"
+)
+
+// headerMessage message of header record written at the start of each new file
+const headerMessage = "log file header"
+
+// parseMode parse octal permission bits like '0640'
+func parseMode(text string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(text, 8, 32)
+
+	if err != nil || mode&^uint64(os.ModePerm) != 0 {
+		return 0, errors.Wrap(slf4go.ErrArgs, "invalid permission %s", text)
+	}
+
+	return os.FileMode(mode), nil
+}
+
+// configHash hash of backend config written into header record
+func configHash(config scf4go.Config) string {
+	var values interface{}
+
+	config.Get().Scan(&values)
+
+	// map keys are sorted by json encoding, so the hash is stable
+	buff, _ := json.Marshal(values)
+
+	hash := sha256.Sum256(buff)
+
+	return hex.EncodeToString(hash[:8])
+}
+
+func defaultApp() string {
+	return filepath.Base(os.Args[0])
+}
+
+// writeHeader write header record into new file, must be called with mutex held and file opened
+func (filebackend *filebackendImpl) writeHeader() error {
+	host, err := os.Hostname()
+
+	if err != nil {
+		host = "localhost"
+	}
+
+	buff, err := filebackend.formatter.Format(&slf4go.EventEntry{
+		Timestamp: time.Now(),
+		Level:     slf4go.INFO,
+		Message:   headerMessage,
+		Source:    "slf4go",
+		Attrs: map[string]interface{}{
+			"@app":         filebackend.App,
+			"@version":     filebackend.Version,
+			"@host":        host,
+			"@pid":         os.Getpid(),
+			"@config_hash": filebackend.configHash,
+		},
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "format header error")
+	}
+
+	n, err := filebackend.file.Write(append(buff, '\n'))
+
+	atomic.AddUint64(&filebackend.written, uint64(n))
+	filebackend.size += int64(n)
+	filebackend.lines++
+	filebackend.dirty = true
+
+	return err
+}
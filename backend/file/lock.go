@@ -0,0 +1,65 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// fileLock advisory lock file shared by processes writing into the same directory,
+// the content is the name of the current log file
+type fileLock struct {
+	path string
+	file *os.File
+}
+
+func openLock(path string, mode os.FileMode) (*fileLock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, mode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileLock{path: path, file: file}, nil
+}
+
+// Lock acquire exclusive lock, blocks until the lock is released by other processes
+func (lock *fileLock) Lock() error {
+	return lockFile(lock.file)
+}
+
+// Unlock .
+func (lock *fileLock) Unlock() error {
+	return unlockFile(lock.file)
+}
+
+// Read read the current file name, must be called with lock held
+func (lock *fileLock) Read() (string, error) {
+	if _, err := lock.file.Seek(0, 0); err != nil {
+		return "", err
+	}
+
+	buff, err := ioutil.ReadAll(lock.file)
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(buff)), nil
+}
+
+// Write write the current file name, must be called with lock held
+func (lock *fileLock) Write(name string) error {
+	if err := lock.file.Truncate(0); err != nil {
+		return err
+	}
+
+	_, err := lock.file.WriteAt([]byte(name), 0)
+
+	return err
+}
+
+// Close .
+func (lock *fileLock) Close() error {
+	return lock.file.Close()
+}
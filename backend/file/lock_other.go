@@ -0,0 +1,19 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package file
+
+import (
+	"os"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/slf4go"
+)
+
+func lockFile(file *os.File) error {
+	return errors.Wrap(slf4go.ErrArgs, "multiprocess mode is not supported on this platform")
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
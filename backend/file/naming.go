@@ -0,0 +1,303 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/slf4go"
+)
+
+// defaultPattern default file name pattern, '{index}' keeps rotations within one second apart
+const defaultPattern = "{name}-%Y%m%dT%H%M%S-{index}.{ext}"
+
+// strftimeLayouts go time layouts and match patterns of supported strftime tokens
+var strftimeLayouts = map[byte][2]string{
+	'Y': {"2006", `\d{4}`},
+	'y': {"06", `\d{2}`},
+	'm': {"01", `\d{2}`},
+	'd': {"02", `\d{2}`},
+	'H': {"15", `\d{2}`},
+	'M': {"04", `\d{2}`},
+	'S': {"05", `\d{2}`},
+	'j': {"002", `\d{3}`},
+}
+
+// fileKey order of log files, rotated files have smaller keys than current file
+type fileKey struct {
+	timestamp time.Time
+	index     int
+}
+
+func (key fileKey) before(other fileKey) bool {
+	if key.timestamp.Equal(other.timestamp) {
+		return key.index < other.index
+	}
+
+	return key.timestamp.Before(other.timestamp)
+}
+
+// naming render and parse log file paths relative to backend directory, pattern tokens are
+// strftime-like '%Y %y %m %d %H %M %S %j %%' and '{name} {ext} {index} {host} {pid} {timestamp}',
+// '/' in pattern creates subdirectories
+type naming struct {
+	path     string // backend directory
+	render   []func(time.Time, int) string
+	regx     *regexp.Regexp // match relative path with '/' separators
+	groups   []string       // time layout of each regx group, empty for index group
+	hasIndex bool           // pattern has '{index}', otherwise index is appended as '.<index>'
+	location *time.Location // time zone of timestamps in file names
+}
+
+func newNaming(path, pattern, name, extension, timestamp string, location *time.Location) (*naming, error) {
+	naming := &naming{path: path, location: location}
+
+	host, err := os.Hostname()
+
+	if err != nil {
+		host = "localhost"
+	}
+
+	extension = strings.TrimPrefix(extension, ".")
+
+	var expr strings.Builder
+
+	expr.WriteString("^")
+
+	literal := func(text string) {
+		naming.render = append(naming.render, func(time.Time, int) string { return text })
+		expr.WriteString(regexp.QuoteMeta(text))
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '%':
+			if i+1 == len(pattern) {
+				return nil, errors.Wrap(slf4go.ErrArgs, "invalid file pattern %s", pattern)
+			}
+
+			i++
+
+			if pattern[i] == '%' {
+				literal("%")
+				continue
+			}
+
+			layout, ok := strftimeLayouts[pattern[i]]
+
+			if !ok {
+				return nil, errors.Wrap(slf4go.ErrArgs, "unknown token %%%c of file pattern %s", pattern[i], pattern)
+			}
+
+			naming.render = append(naming.render, func(t time.Time, index int) string { return t.Format(layout[0]) })
+			naming.groups = append(naming.groups, layout[0])
+			expr.WriteString("(" + layout[1] + ")")
+		case '{':
+			end := strings.IndexByte(pattern[i:], '}')
+
+			if end == -1 {
+				return nil, errors.Wrap(slf4go.ErrArgs, "invalid file pattern %s", pattern)
+			}
+
+			token := pattern[i+1 : i+end]
+			i += end
+
+			switch token {
+			case "name":
+				literal(name)
+			case "ext":
+				literal(extension)
+			case "host", "hostname":
+				literal(host)
+			case "pid":
+				// files of other processes sharing the directory match too
+				naming.render = append(naming.render, func(time.Time, int) string { return strconv.Itoa(os.Getpid()) })
+				expr.WriteString(`\d+`)
+			case "index":
+				naming.hasIndex = true
+				naming.render = append(naming.render, func(t time.Time, index int) string { return strconv.Itoa(index) })
+				naming.groups = append(naming.groups, "")
+				expr.WriteString(`(\d+)`)
+			case "timestamp":
+				naming.render = append(naming.render, func(t time.Time, index int) string { return t.Format(timestamp) })
+				naming.groups = append(naming.groups, timestamp)
+				expr.WriteString(`(.+?)`)
+			default:
+				return nil, errors.Wrap(slf4go.ErrArgs, "unknown token {%s} of file pattern %s", token, pattern)
+			}
+		default:
+			literal(pattern[i : i+1])
+		}
+	}
+
+	if !naming.hasIndex {
+		naming.groups = append(naming.groups, "")
+		expr.WriteString(`(?:\.(\d+))?`)
+	}
+
+	expr.WriteString(`(` + regexp.QuoteMeta(compressedExtension) + `)?$`)
+
+	regx, err := regexp.Compile(expr.String())
+
+	if err != nil {
+		return nil, errors.Wrap(err, "compile file pattern %s error", pattern)
+	}
+
+	naming.regx = regx
+
+	return naming, nil
+}
+
+// file render file path of rotation timestamp and index
+func (naming *naming) file(t time.Time, index int) string {
+	var buff strings.Builder
+
+	for _, render := range naming.render {
+		buff.WriteString(render(t, index))
+	}
+
+	if !naming.hasIndex && index > 0 {
+		buff.WriteString(fmt.Sprintf(".%d", index))
+	}
+
+	return filepath.Join(naming.path, filepath.FromSlash(buff.String()))
+}
+
+// parse parse rotation key of file path in backend directory
+func (naming *naming) parse(path string) (fileKey, bool, bool) {
+	rel, err := filepath.Rel(naming.path, path)
+
+	if err != nil {
+		return fileKey{}, false, false
+	}
+
+	matches := naming.regx.FindStringSubmatch(filepath.ToSlash(rel))
+
+	if matches == nil {
+		return fileKey{}, false, false
+	}
+
+	var key fileKey
+
+	var values, layouts []string
+
+	for i, layout := range naming.groups {
+		value := matches[i+1]
+
+		if layout == "" {
+			if value != "" {
+				key.index, _ = strconv.Atoi(value)
+			}
+
+			continue
+		}
+
+		values = append(values, value)
+		layouts = append(layouts, layout)
+	}
+
+	if len(layouts) > 0 {
+		timestamp, err := time.ParseInLocation(strings.Join(layouts, "|"), strings.Join(values, "|"), naming.location)
+
+		if err != nil {
+			return fileKey{}, false, false
+		}
+
+		key.timestamp = timestamp
+	}
+
+	return key, matches[len(matches)-1] != "", true
+}
+
+// list list log files sorted by rotation key
+func (naming *naming) list() ([]*logFile, error) {
+	var files []*logFile
+
+	err := filepath.Walk(naming.path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// the file may be removed by other process
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			return err
+		}
+
+		if info.IsDir() || info.Mode()&os.ModeSymlink != 0 {
+			return nil
+		}
+
+		key, compressed, ok := naming.parse(path)
+
+		if !ok {
+			return nil
+		}
+
+		files = append(files, &logFile{
+			path:       path,
+			key:        key,
+			modTime:    info.ModTime(),
+			size:       info.Size(),
+			compressed: compressed,
+		})
+
+		return nil
+	})
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].key.before(files[j].key)
+	})
+
+	return files, err
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+
+	return err == nil
+}
+
+// next get the path of new file rotated at timestamp, the index is increased until the path is not used
+func (naming *naming) next(timestamp time.Time) string {
+	for index := 0; ; index++ {
+		path := naming.file(timestamp, index)
+
+		if !exists(path) && !exists(path+compressedExtension) {
+			return path
+		}
+	}
+}
+
+// updateSymlink point symlink at path, the symlink is replaced atomically
+func updateSymlink(symlink string, path string) error {
+	target, err := filepath.Rel(filepath.Dir(symlink), path)
+
+	if err != nil {
+		target = path
+	}
+
+	if current, err := os.Readlink(symlink); err == nil && current == target {
+		return nil
+	}
+
+	tmp := fmt.Sprintf("%s.%d.tmp", symlink, os.Getpid())
+
+	os.Remove(tmp)
+
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, symlink); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
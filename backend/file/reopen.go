@@ -0,0 +1,115 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/libs4go/errors"
+)
+
+// fixedPattern default file name pattern of fixed mode
+const fixedPattern = "{name}.{ext}"
+
+var backendsMutex sync.Mutex
+var backends = make(map[*filebackendImpl]struct{})
+var signalOnce sync.Once
+
+// register track configured backend for package level Reopen, fixed mode backends are reopened on SIGHUP
+func register(filebackend *filebackendImpl) {
+	backendsMutex.Lock()
+	defer backendsMutex.Unlock()
+
+	backends[filebackend] = struct{}{}
+
+	if filebackend.Fixed {
+		signalOnce.Do(func() {
+			notifyReopen(func() {
+				if err := Reopen(); err != nil {
+					println(err.Error())
+				}
+			})
+		})
+	}
+}
+
+func unregister(filebackend *filebackendImpl) {
+	backendsMutex.Lock()
+	defer backendsMutex.Unlock()
+
+	delete(backends, filebackend)
+}
+
+// Reopen reopen files of all configured file backends, it is called on SIGHUP if any backend is in fixed mode
+func Reopen() error {
+	backendsMutex.Lock()
+
+	reopen := make([]*filebackendImpl, 0, len(backends))
+
+	for filebackend := range backends {
+		reopen = append(reopen, filebackend)
+	}
+
+	backendsMutex.Unlock()
+
+	var err error
+
+	for _, filebackend := range reopen {
+		if reopenErr := filebackend.Reopen(); err == nil {
+			err = reopenErr
+		}
+	}
+
+	return err
+}
+
+// Reopen close current file and reopen it by path, call it after external tools moved the file,
+// buffered lines are written into the file at the path
+func (filebackend *filebackendImpl) Reopen() error {
+	filebackend.mutex.Lock()
+	defer filebackend.mutex.Unlock()
+
+	if err := filebackend.closeFile(); err != nil {
+		println(fmt.Sprintf("close file %s error %s", filebackend.currentPath, err))
+		atomic.AddUint64(&filebackend.failed, 1)
+	}
+
+	// shared files are opened with lock held by next write
+	if filebackend.MultiProcess {
+		return nil
+	}
+
+	if err := filebackend.open(); err != nil {
+		return errors.Wrap(err, "reopen file %s error", filebackend.currentPath)
+	}
+
+	return nil
+}
+
+// checkReplaced close current file if it was moved or removed, so the next open creates a new one,
+// truncated files are kept open and the size is reset
+func (filebackend *filebackendImpl) checkReplaced() {
+	if filebackend.file == nil {
+		return
+	}
+
+	current, err := filebackend.file.Stat()
+
+	if err != nil {
+		filebackend.closeHandle()
+		return
+	}
+
+	info, err := os.Stat(filebackend.currentPath)
+
+	if err != nil || !os.SameFile(current, info) {
+		filebackend.closeHandle()
+		return
+	}
+
+	if info.Size() < filebackend.size {
+		// truncated by copytruncate, appended lines go to the new end
+		filebackend.size = info.Size()
+	}
+}
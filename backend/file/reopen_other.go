@@ -0,0 +1,8 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package file
+
+// notifyReopen SIGHUP is not supported, call Reopen instead
+func notifyReopen(reopen func()) {
+}
@@ -0,0 +1,23 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package file
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyReopen call reopen on SIGHUP, the process is no longer terminated by SIGHUP
+func notifyReopen(reopen func()) {
+	signals := make(chan os.Signal, 1)
+
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		for range signals {
+			reopen()
+		}
+	}()
+}
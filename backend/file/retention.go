@@ -0,0 +1,219 @@
+package file
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const compressedExtension = ".gz"
+
+// logFile log file found in backend directory
+type logFile struct {
+	path       string
+	key        fileKey // rotation key parsed from file name
+	modTime    time.Time
+	size       int64
+	compressed bool
+}
+
+// retention policies of rotated files, zero value means unlimited
+type retention struct {
+	maxFiles     int           // max number of rotated files
+	maxAge       time.Duration // max age of rotated files since last modified
+	maxTotalSize int64         // max total size of log files including current file
+	compress     bool          // gzip rotated files
+}
+
+func (policy retention) enabled() bool {
+	return policy.maxFiles > 0 || policy.maxAge > 0 || policy.maxTotalSize > 0 || policy.compress
+}
+
+// compressFile gzip file into '<path>.gz' and remove it, the modification time is kept for max age retention
+func compressFile(path string) (err error) {
+	source, err := os.Open(path)
+
+	if err != nil {
+		return err
+	}
+
+	defer source.Close()
+
+	info, err := source.Stat()
+
+	if err != nil {
+		return err
+	}
+
+	tmp := path + compressedExtension + ".tmp"
+
+	// O_EXCL prevents processes sharing the directory compressing the same file
+	target, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode())
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			target.Close()
+			os.Remove(tmp)
+		}
+	}()
+
+	writer := gzip.NewWriter(target)
+
+	if _, err = io.Copy(writer, source); err != nil {
+		return err
+	}
+
+	if err = writer.Close(); err != nil {
+		return err
+	}
+
+	if err = target.Sync(); err != nil {
+		return err
+	}
+
+	if err = target.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Chtimes(tmp, info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmp, path+compressedExtension); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// triggerCleanup schedule cleanup of rotated files, must be called with mutex held
+func (filebackend *filebackendImpl) triggerCleanup() {
+	if filebackend.cleanup == nil {
+		return
+	}
+
+	select {
+	case filebackend.cleanup <- struct{}{}:
+	default:
+		// a cleanup is already pending
+	}
+}
+
+// currentFile get naming, retention and the key of current file, files rotated at or after
+// the key are never touched
+func (filebackend *filebackendImpl) currentFile() (*naming, retention, fileKey) {
+	filebackend.mutex.Lock()
+	defer filebackend.mutex.Unlock()
+
+	naming := filebackend.naming
+
+	current, _, ok := naming.parse(filebackend.currentPath)
+
+	if !ok {
+		current = fileKey{timestamp: filebackend.currentTimestamp}
+	}
+
+	if filebackend.lock == nil || filebackend.lock.Lock() != nil {
+		return naming, filebackend.retention, current
+	}
+
+	defer filebackend.lock.Unlock()
+
+	// the current file shared by processes may be newer than ours
+	if name, err := filebackend.lock.Read(); err == nil && name != "" {
+		if key, _, ok := naming.parse(filepath.Join(naming.path, name)); ok && current.before(key) {
+			current = key
+		}
+	}
+
+	return naming, filebackend.retention, current
+}
+
+// clean compress rotated files and remove files exceeding retention policies, all errors are returned
+func (filebackend *filebackendImpl) clean() []error {
+	naming, policy, current := filebackend.currentFile()
+
+	if !policy.enabled() {
+		return nil
+	}
+
+	files, err := naming.list()
+
+	if err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+
+	var rotated []*logFile
+
+	var total int64
+
+	for _, file := range files {
+		total += file.size
+
+		if file.key.before(current) {
+			rotated = append(rotated, file)
+		}
+	}
+
+	remove := func(file *logFile) {
+		if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+			return
+		}
+
+		total -= file.size
+
+		// remove empty date subdirectories, it fails if the directory is not empty
+		if dir := filepath.Dir(file.path); filepath.Clean(dir) != filepath.Clean(naming.path) {
+			os.Remove(dir)
+		}
+	}
+
+	var kept []*logFile
+
+	for _, file := range rotated {
+		if policy.maxAge > 0 && time.Since(file.modTime) > policy.maxAge {
+			remove(file)
+			continue
+		}
+
+		kept = append(kept, file)
+	}
+
+	if policy.maxFiles > 0 && len(kept) > policy.maxFiles {
+		for _, file := range kept[:len(kept)-policy.maxFiles] {
+			remove(file)
+		}
+
+		kept = kept[len(kept)-policy.maxFiles:]
+	}
+
+	for policy.maxTotalSize > 0 && total > policy.maxTotalSize && len(kept) > 0 {
+		remove(kept[0])
+		kept = kept[1:]
+	}
+
+	if !policy.compress {
+		return errs
+	}
+
+	for _, file := range kept {
+		if file.compressed {
+			continue
+		}
+
+		if err := compressFile(file.path); err != nil && !os.IsExist(err) {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,225 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rotation reasons
+const (
+	RotateSize  = "size"
+	RotateTime  = "time"
+	RotateLines = "lines"
+)
+
+// RotateEvent log file rotation of file backend
+type RotateEvent struct {
+	Backend  string    // backend name
+	Previous string    // path of the rotated file
+	Current  string    // path of the new file, it is created by next write
+	Reason   string    // one of RotateSize, RotateTime and RotateLines
+	Time     time.Time // rotation time
+}
+
+var callbacksMutex sync.RWMutex
+var callbacks []func(event *RotateEvent)
+
+// OnRotate register callback called in background after rotation of any file backend,
+// callbacks are called in rotation order and must not block
+func OnRotate(callback func(event *RotateEvent)) {
+	callbacksMutex.Lock()
+	defer callbacksMutex.Unlock()
+
+	callbacks = append(callbacks, callback)
+}
+
+func notifyRotate(event *RotateEvent) {
+	callbacksMutex.RLock()
+	defer callbacksMutex.RUnlock()
+
+	for _, callback := range callbacks {
+		callback(event)
+	}
+}
+
+// rotationAt get the time rotating file started at start, aligned rotation happens at multiples
+// of rotation time since midnight of the configured time zone
+func (filebackend *filebackendImpl) rotationAt(start time.Time) time.Time {
+	period := filebackend.RotationTime
+
+	if period <= 0 {
+		// time rotation is disabled
+		return start.AddDate(100, 0, 0)
+	}
+
+	if !filebackend.RotationAligned {
+		return start.Add(period)
+	}
+
+	local := start.In(filebackend.location)
+
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, filebackend.location)
+
+	const day = 24 * time.Hour
+
+	if period%day == 0 {
+		// days are not always 24 hours long across DST changes
+		return midnight.AddDate(0, 0, int(period/day))
+	}
+
+	return midnight.Add((local.Sub(midnight)/period + 1) * period)
+}
+
+// rotationReason check if current file should be rotated, must be called with mutex held
+func (filebackend *filebackendImpl) rotationReason() string {
+	switch {
+	case filebackend.Fixed:
+		return ""
+	case filebackend.size+int64(len(filebackend.buffer)) > filebackend.MaxSize:
+		return RotateSize
+	case filebackend.MaxLines > 0 && filebackend.lines >= filebackend.MaxLines:
+		return RotateLines
+	case !time.Now().Before(filebackend.nextRotation):
+		return RotateTime
+	}
+
+	return ""
+}
+
+// rotate close current file and switch to new file, must be called with mutex held
+func (filebackend *filebackendImpl) rotate(reason string) {
+	previous := filebackend.currentPath
+
+	if err := filebackend.closeFile(); err != nil {
+		println(fmt.Sprintf("close file %s error %s", filebackend.currentPath, err))
+		atomic.AddUint64(&filebackend.failed, 1)
+	}
+
+	filebackend.newFilePath()
+	filebackend.rotated(reason, previous)
+}
+
+// rotated queue rotation event handled in background, must be called with mutex held
+func (filebackend *filebackendImpl) rotated(reason string, previous string) {
+	if filebackend.events == nil {
+		return
+	}
+
+	event := &RotateEvent{
+		Backend:  filebackend.backendName,
+		Previous: previous,
+		Current:  filebackend.currentPath,
+		Reason:   reason,
+		Time:     time.Now(),
+	}
+
+	select {
+	case filebackend.events <- event:
+	default:
+		println(fmt.Sprintf("rotation event of %s dropped", previous))
+	}
+}
+
+// rotationLoop rotate files by time even if nothing is written
+func (filebackend *filebackendImpl) rotationLoop(stop chan struct{}) {
+	for {
+		filebackend.mutex.Lock()
+		next := filebackend.nextRotation
+		filebackend.mutex.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		filebackend.mutex.Lock()
+
+		if !time.Now().Before(filebackend.nextRotation) {
+			filebackend.rotateByTimer()
+		}
+
+		filebackend.mutex.Unlock()
+	}
+}
+
+// rotateByTimer must be called with mutex held
+func (filebackend *filebackendImpl) rotateByTimer() {
+	if filebackend.MultiProcess {
+		// the shared file is rotated with lock held unless it is empty
+		if err := filebackend.flushShared(); err != nil {
+			println(fmt.Sprintf("write to file %s error %s", filebackend.currentPath, err))
+			atomic.AddUint64(&filebackend.failed, 1)
+		}
+
+		filebackend.buffer = filebackend.buffer[:0]
+
+		if !time.Now().Before(filebackend.nextRotation) {
+			// keep the empty file for next period
+			filebackend.nextRotation = filebackend.rotationAt(time.Now())
+		}
+
+		return
+	}
+
+	if filebackend.size > 0 || len(filebackend.buffer) > 0 {
+		filebackend.rotate(RotateTime)
+		return
+	}
+
+	// nothing written into current file, the new file is created by next write
+	filebackend.newFilePath()
+}
+
+// backgroundLoop notify rotation callbacks and clean rotated files
+func (filebackend *filebackendImpl) backgroundLoop(events chan *RotateEvent, cleanup chan struct{}, stop chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case event := <-events:
+			notifyRotate(event)
+		case <-cleanup:
+		}
+
+		for _, err := range filebackend.clean() {
+			println(fmt.Sprintf("clean rotated log files error %s", err))
+			atomic.AddUint64(&filebackend.failed, 1)
+		}
+	}
+}
+
+// countLines count lines of existing file resumed
+func countLines(path string) int64 {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return 0
+	}
+
+	defer file.Close()
+
+	var lines int64
+
+	buff := make([]byte, 32*1024)
+
+	for {
+		n, err := file.Read(buff)
+
+		for _, c := range buff[:n] {
+			if c == '\n' {
+				lines++
+			}
+		}
+
+		if err != nil {
+			return lines
+		}
+	}
+}
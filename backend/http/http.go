@@ -0,0 +1,464 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	nethttp "net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/scf4go"
+	"github.com/libs4go/slf4go"
+	"github.com/libs4go/slf4go/internal/queue"
+)
+
+// body encodings
+const (
+	encodingJSON   = "json"   // JSON array of entries
+	encodingNDJSON = "ndjson" // newline delimited entries
+)
+
+var errStatus = errors.New("unexpected http status")
+
+// httpImpl post batches of entries to remote endpoint, batches are sent by a background loop
+type httpImpl struct {
+	mutex        sync.Mutex
+	URL          string            `json:"url"`
+	Headers      map[string]string `json:"headers"`
+	Encoding     string            `json:"encoding"`
+	Compress     bool              `json:"compress"`
+	Timeout      time.Duration     `json:"timeout"`
+	BatchSize    int               `json:"batch_size"`
+	BatchBytes   int64             `json:"batch_bytes"`
+	BatchLatency time.Duration     `json:"batch_latency"`
+	QueueSize    int64             `json:"queue_size"` // max bytes of queued entries
+	Overflow     string            `json:"overflow"`
+	MaxRetries   int               `json:"max_retries"` // negative retries forever
+	BackoffMin   time.Duration     `json:"backoff_min"`
+	BackoffMax   time.Duration     `json:"backoff_max"`
+	formatter    slf4go.Formatter
+	client       *nethttp.Client
+	queue        *queue.Queue // formatted entries
+	failed       uint64
+	written      uint64
+	stop         chan struct{} // abort sending after Close timeout
+	exited       chan struct{}
+	initOnce     sync.Once
+}
+
+func newHTTP() *httpImpl {
+	http := &httpImpl{
+		Encoding:     encodingJSON,
+		Timeout:      10 * time.Second,
+		BatchSize:    100,
+		BatchBytes:   1024 * 1024,
+		BatchLatency: time.Second,
+		QueueSize:    8 * 1024 * 1024,
+		Overflow:     queue.DropOldest,
+		MaxRetries:   5,
+		BackoffMin:   100 * time.Millisecond,
+		BackoffMax:   30 * time.Second,
+		formatter:    slf4go.JSONFormatter,
+		client:       &nethttp.Client{Timeout: 10 * time.Second},
+		stop:         make(chan struct{}),
+		exited:       make(chan struct{}),
+	}
+
+	http.queue = queue.New(http.queueOptions())
+
+	return http
+}
+
+// queueOptions must be called with lock held
+func (http *httpImpl) queueOptions() queue.Options {
+	return queue.Options{
+		MaxBytes:     http.QueueSize,
+		Overflow:     http.Overflow,
+		BatchSize:    http.BatchSize,
+		BatchBytes:   http.BatchBytes,
+		BatchLatency: http.BatchLatency,
+	}
+}
+
+func (http *httpImpl) Send(entry *slf4go.EventEntry) {
+	http.start()
+
+	http.mutex.Lock()
+	buff, err := http.formatter.Format(entry)
+
+	if err != nil {
+		http.failed++
+		http.mutex.Unlock()
+		println(fmt.Sprintf("format event entry error %s", err))
+		return
+	}
+
+	http.mutex.Unlock()
+
+	http.queue.Push(buff)
+}
+
+// request request settings snapshot used by the send loop
+type request struct {
+	client     *nethttp.Client
+	url        string
+	headers    map[string]string
+	encoding   string
+	compress   bool
+	maxRetries int
+	backoffMin time.Duration
+	backoffMax time.Duration
+}
+
+// encode build request body of batch
+func (request *request) encode(batch [][]byte) ([]byte, error) {
+	var body []byte
+
+	if request.encoding == encodingNDJSON {
+		body = append(bytes.Join(batch, []byte("\n")), '\n')
+	} else {
+		body = append(append([]byte("["), bytes.Join(batch, []byte(","))...), ']')
+	}
+
+	if !request.compress {
+		return body, nil
+	}
+
+	var buff bytes.Buffer
+
+	writer := gzip.NewWriter(&buff)
+
+	if _, err := writer.Write(body); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buff.Bytes(), nil
+}
+
+// post send body once, the returned delay is the Retry-After of retryable responses
+func (request *request) post(body []byte) (retry bool, delay time.Duration, err error) {
+	req, err := nethttp.NewRequest(nethttp.MethodPost, request.url, bytes.NewReader(body))
+
+	if err != nil {
+		return false, 0, err
+	}
+
+	if request.encoding == encodingNDJSON {
+		req.Header.Set("Content-Type", "application/x-ndjson")
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if request.compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	for key, value := range request.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := request.client.Do(req)
+
+	if err != nil {
+		return true, 0, err
+	}
+
+	// drain body to reuse the connection
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, 0, nil
+	}
+
+	err = errors.Wrap(errStatus, "post %s status %s", request.url, resp.Status)
+
+	if resp.StatusCode != nethttp.StatusTooManyRequests && resp.StatusCode < 500 {
+		return false, 0, err
+	}
+
+	return true, retryAfter(resp.Header.Get("Retry-After")), err
+}
+
+// retryAfter parse Retry-After header of seconds or http date, 0 is returned if absent or invalid
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := nethttp.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+
+	return 0
+}
+
+// sleep wait delay, return false if sending is aborted
+func (http *httpImpl) sleep(delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-http.stop:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// deliver post batch with retries, return false if the batch failed
+func (http *httpImpl) deliver(request *request, batch [][]byte) (bool, int) {
+	body, err := request.encode(batch)
+
+	if err != nil {
+		println(fmt.Sprintf("encode batch error %s", err))
+		return false, 0
+	}
+
+	delay := request.backoffMin
+
+	for attempt := 0; ; attempt++ {
+		retry, retryDelay, err := request.post(body)
+
+		if err == nil {
+			return true, len(body)
+		}
+
+		if !retry || (request.maxRetries >= 0 && attempt >= request.maxRetries) {
+			println(fmt.Sprintf("post %d events error %s", len(batch), err))
+			return false, 0
+		}
+
+		if retryDelay <= 0 {
+			retryDelay = delay
+
+			if delay *= 2; delay > request.backoffMax {
+				delay = request.backoffMax
+			}
+		}
+
+		if !http.sleep(retryDelay) {
+			return false, 0
+		}
+	}
+}
+
+func (http *httpImpl) sendLoop() {
+	defer close(http.exited)
+
+	for {
+		batch := http.queue.Take()
+
+		if batch == nil {
+			return
+		}
+
+		http.mutex.Lock()
+
+		request := &request{
+			client:     http.client,
+			url:        http.URL,
+			headers:    http.Headers,
+			encoding:   http.Encoding,
+			compress:   http.Compress,
+			maxRetries: http.MaxRetries,
+			backoffMin: http.BackoffMin,
+			backoffMax: http.BackoffMax,
+		}
+
+		http.mutex.Unlock()
+
+		ok, written := http.deliver(request, batch)
+
+		select {
+		case <-http.stop:
+			// Close timeout, the batch and queued entries are dropped
+			if ok {
+				http.queue.Done()
+			} else {
+				http.queue.Drop()
+			}
+
+			http.queue.Abort()
+
+			return
+		default:
+		}
+
+		http.mutex.Lock()
+
+		if ok {
+			http.written += uint64(written)
+		} else {
+			http.failed += uint64(len(batch))
+		}
+
+		http.mutex.Unlock()
+
+		http.queue.Done()
+	}
+}
+
+func (http *httpImpl) start() {
+	http.initOnce.Do(func() {
+		go http.sendLoop()
+	})
+}
+
+func (http *httpImpl) Stats() slf4go.BackendStats {
+	stats := http.queue.Stats()
+
+	http.mutex.Lock()
+	defer http.mutex.Unlock()
+
+	stats.Written = http.written
+	stats.Failed = http.failed
+
+	return stats
+}
+
+// Flush wait until all events accepted before calling Flush are posted, failed or dropped, the wait
+// is limited to timeout if ctx has no deadline, so Factory.Sync returns while retrying
+func (http *httpImpl) Flush(ctx context.Context) error {
+	http.start()
+
+	if _, ok := ctx.Deadline(); !ok {
+		http.mutex.Lock()
+		timeout := http.Timeout
+		http.mutex.Unlock()
+
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	return http.queue.Flush(ctx)
+}
+
+func (http *httpImpl) Sync() {
+	if err := http.Flush(context.Background()); err != nil {
+		println(fmt.Sprintf("sync http backend error: %s", err))
+	}
+}
+
+// Close stop accepting events and drain the queue, queued events are dropped if ctx done
+func (http *httpImpl) Close(ctx context.Context) error {
+	http.start()
+
+	http.queue.Close()
+
+	select {
+	case <-http.exited:
+		return nil
+	case <-ctx.Done():
+	}
+
+	http.mutex.Lock()
+
+	select {
+	case <-http.stop:
+	default:
+		close(http.stop)
+	}
+
+	http.mutex.Unlock()
+
+	return errors.Wrap(ctx.Err(), "drain http events error")
+}
+
+func (http *httpImpl) Config(config scf4go.Config) error {
+	url := config.Get("url").String("")
+
+	if url == "" {
+		return errors.Wrap(slf4go.ErrArgs, "http backend url is required")
+	}
+
+	encoding := strings.ToLower(config.Get("encoding").String(encodingJSON))
+
+	switch encoding {
+	case encodingJSON, encodingNDJSON:
+	default:
+		return errors.Wrap(slf4go.ErrArgs, "unknown http encoding %s", encoding)
+	}
+
+	// both encodings require each entry to be one JSON object
+	switch format := config.Get("format").String("json"); format {
+	case "json", "clef":
+	default:
+		return errors.Wrap(slf4go.ErrArgs, "http %s encoding requires json or clef format, got %s", encoding, format)
+	}
+
+	formatter, err := slf4go.NewFormatter(config, "json")
+
+	if err != nil {
+		return err
+	}
+
+	overflow := strings.ToLower(config.Get("overflow").String(queue.DropOldest))
+
+	switch overflow {
+	case queue.DropNewest, queue.DropOldest:
+	default:
+		return errors.Wrap(slf4go.ErrArgs, "unknown http overflow policy %s", overflow)
+	}
+
+	batchBytes, err := slf4go.ConfigSize(config.Get("batch_bytes"), 1024*1024)
+
+	if err != nil {
+		return err
+	}
+
+	queueSize, err := slf4go.ConfigSize(config.Get("queue_size"), 8*1024*1024)
+
+	if err != nil {
+		return err
+	}
+
+	timeout := config.Get("timeout").Duration(10 * time.Second)
+
+	http.mutex.Lock()
+	defer http.mutex.Unlock()
+
+	http.URL = url
+	http.Headers = config.Get("headers").StringMap(nil)
+	http.Encoding = encoding
+	http.Compress = config.Get("compress").Bool(false)
+	http.Timeout = timeout
+	http.BatchSize = config.Get("batch_size").Int(100)
+	http.BatchBytes = batchBytes
+	http.BatchLatency = config.Get("batch_latency").Duration(time.Second)
+	http.QueueSize = queueSize
+	http.Overflow = overflow
+	http.MaxRetries = config.Get("max_retries").Int(5)
+	http.BackoffMin = config.Get("backoff_min").Duration(100 * time.Millisecond)
+	http.BackoffMax = config.Get("backoff_max").Duration(30 * time.Second)
+	http.formatter = formatter
+	http.client = &nethttp.Client{Timeout: timeout}
+	http.queue.SetOptions(http.queueOptions())
+
+	return nil
+}
+
+func init() {
+	slf4go.RegisterBackendType("http", func(name string) slf4go.Backend {
+		return newHTTP()
+	})
+}
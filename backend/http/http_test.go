@@ -0,0 +1,279 @@
+package http
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	nethttp "net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libs4go/slf4go"
+	"github.com/libs4go/slf4go/slf4gotest"
+	"github.com/stretchr/testify/require"
+)
+
+func newEntry(i int) *slf4go.EventEntry {
+	return slf4gotest.Entry(slf4go.INFO, fmt.Sprintf("message %d", i), "id", i)
+}
+
+// collector record posted batches, responses are taken from statuses before answering 200
+type collector struct {
+	mutex    sync.Mutex
+	statuses []int
+	requests []*nethttp.Request
+	batches  [][]map[string]interface{}
+}
+
+func (collector *collector) ServeHTTP(writer nethttp.ResponseWriter, request *nethttp.Request) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	collector.requests = append(collector.requests, request)
+
+	if len(collector.statuses) > 0 {
+		status := collector.statuses[0]
+		collector.statuses = collector.statuses[1:]
+
+		if status == nethttp.StatusTooManyRequests {
+			writer.Header().Set("Retry-After", "1")
+		}
+
+		writer.WriteHeader(status)
+		return
+	}
+
+	var reader io.Reader = request.Body
+
+	if request.Header.Get("Content-Encoding") == "gzip" {
+		gzipReader, err := gzip.NewReader(request.Body)
+
+		if err != nil {
+			writer.WriteHeader(nethttp.StatusBadRequest)
+			return
+		}
+
+		reader = gzipReader
+	}
+
+	var batch []map[string]interface{}
+
+	if request.Header.Get("Content-Type") == "application/x-ndjson" {
+		scanner := bufio.NewScanner(reader)
+
+		for scanner.Scan() {
+			var entry map[string]interface{}
+
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				writer.WriteHeader(nethttp.StatusBadRequest)
+				return
+			}
+
+			batch = append(batch, entry)
+		}
+	} else if err := json.NewDecoder(reader).Decode(&batch); err != nil {
+		writer.WriteHeader(nethttp.StatusBadRequest)
+		return
+	}
+
+	collector.batches = append(collector.batches, batch)
+}
+
+func (collector *collector) sizes() []int {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	var sizes []int
+
+	for _, batch := range collector.batches {
+		sizes = append(sizes, len(batch))
+	}
+
+	return sizes
+}
+
+func TestBatch(t *testing.T) {
+	collector := &collector{}
+
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	http := newHTTP()
+
+	err := http.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+url: %s
+batch_size: 3
+batch_latency: 1h
+headers:
+  X-Token: secret
+`, server.URL)))
+
+	require.NoError(t, err)
+
+	defer http.Close(context.Background())
+
+	for i := 0; i < 7; i++ {
+		http.Send(newEntry(i))
+	}
+
+	require.Eventually(t, func() bool {
+		return fmt.Sprint(collector.sizes()) == "[3 3]"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, http.Flush(context.Background()))
+	require.Equal(t, []int{3, 3, 1}, collector.sizes())
+
+	require.Equal(t, "message 6", collector.batches[2][0]["@m"])
+	require.Equal(t, "secret", collector.requests[0].Header.Get("X-Token"))
+	require.Equal(t, "application/json", collector.requests[0].Header.Get("Content-Type"))
+
+	stats := http.Stats()
+	require.Equal(t, uint64(7), stats.Enqueued)
+	require.Zero(t, stats.Depth)
+	require.Zero(t, stats.Failed)
+	require.True(t, stats.Written > 0)
+
+	// entries must be JSON objects
+	err = newHTTP().Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+url: %s
+format: text
+`, server.URL)))
+
+	require.Error(t, err)
+}
+
+func TestRetry(t *testing.T) {
+	collector := &collector{
+		statuses: []int{nethttp.StatusServiceUnavailable, nethttp.StatusTooManyRequests},
+	}
+
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	http := newHTTP()
+
+	err := http.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+url: %s
+encoding: ndjson
+compress: true
+batch_latency: 0
+backoff_min: 10ms
+`, server.URL)))
+
+	require.NoError(t, err)
+
+	defer http.Close(context.Background())
+
+	start := time.Now()
+
+	http.Send(newEntry(1))
+
+	require.NoError(t, http.Flush(context.Background()))
+
+	// Retry-After of 429 is honored
+	require.True(t, time.Since(start) >= time.Second)
+	require.Len(t, collector.requests, 3)
+	require.Equal(t, []int{1}, collector.sizes())
+	require.Equal(t, "message 1", collector.batches[0][0]["@m"])
+	require.Equal(t, "gzip", collector.requests[2].Header.Get("Content-Encoding"))
+
+	// client errors are not retried
+	collector.mutex.Lock()
+	collector.statuses = []int{nethttp.StatusBadRequest}
+	collector.mutex.Unlock()
+
+	http.Send(newEntry(2))
+
+	require.NoError(t, http.Flush(context.Background()))
+	require.Len(t, collector.requests, 4)
+	require.Equal(t, uint64(1), http.Stats().Failed)
+}
+
+func TestOverflow(t *testing.T) {
+	collector := &collector{
+		statuses: []int{nethttp.StatusInternalServerError},
+	}
+
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	http := newHTTP()
+
+	err := http.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+url: %s
+batch_size: 1
+batch_latency: 0
+queue_size: 1KB
+backoff_min: 1h
+`, server.URL)))
+
+	require.NoError(t, err)
+
+	for i := 0; i < 200; i++ {
+		http.Send(newEntry(i))
+	}
+
+	require.True(t, http.queue.Bytes() <= 1024)
+	require.True(t, http.Stats().Dropped > 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	require.Error(t, http.Close(ctx))
+	cancel()
+
+	<-http.exited
+
+	stats := http.Stats()
+	require.Zero(t, stats.Depth)
+	require.Equal(t, uint64(200), stats.Dropped)
+}
+
+func TestSyncOutage(t *testing.T) {
+	server := httptest.NewServer(nethttp.HandlerFunc(func(writer nethttp.ResponseWriter, request *nethttp.Request) {
+		writer.WriteHeader(nethttp.StatusServiceUnavailable)
+	}))
+
+	defer server.Close()
+
+	http := newHTTP()
+
+	err := http.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+url: %s
+timeout: 100ms
+batch_latency: 0
+max_retries: -1
+backoff_min: 10ms
+`, server.URL)))
+
+	require.NoError(t, err)
+
+	factory := slf4go.New()
+	factory.RegisterBackend("http", http)
+	factory.ConfigDefault("http", slf4go.INFO)
+
+	factory.Get("test").I("lost")
+
+	// Factory.Sync flushes without deadline, the wait is limited by timeout
+	done := make(chan struct{})
+
+	go func() {
+		factory.Sync()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "sync blocked while retrying")
+	}
+
+	require.Equal(t, 1, http.Stats().Depth)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	http.Close(ctx)
+	cancel()
+}
@@ -0,0 +1,175 @@
+package network
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"time"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/scf4go"
+	"github.com/libs4go/slf4go"
+)
+
+// datagram check if each write of network is one message
+func datagram(network string) bool {
+	switch network {
+	case "udp", "udp4", "udp6", "unixgram":
+		return true
+	}
+
+	return false
+}
+
+// dialer connection settings snapshot used by the send loop
+type dialer struct {
+	network  string
+	address  string
+	timeout  time.Duration
+	compress bool
+	tls      *tls.Config
+}
+
+func (dialer *dialer) dial() (*connection, error) {
+	netDialer := &net.Dialer{Timeout: dialer.timeout}
+
+	var conn net.Conn
+	var err error
+
+	if dialer.tls != nil {
+		conn, err = tls.DialWithDialer(netDialer, dialer.network, dialer.address, dialer.tls)
+	} else {
+		conn, err = netDialer.Dial(dialer.network, dialer.address)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	connection := &connection{
+		conn:     conn,
+		datagram: datagram(dialer.network),
+		timeout:  dialer.timeout,
+	}
+
+	if dialer.compress && !connection.datagram {
+		// one gzip stream per connection, flushed after each batch
+		connection.gzip = gzip.NewWriter(conn)
+	}
+
+	connection.compress = dialer.compress
+
+	return connection, nil
+}
+
+// connection connection owned by the send loop
+type connection struct {
+	conn     net.Conn
+	gzip     *gzip.Writer
+	datagram bool
+	compress bool
+	timeout  time.Duration
+}
+
+// write send lines, each line is one datagram on datagram networks
+func (connection *connection) write(lines [][]byte) error {
+	if connection.timeout > 0 {
+		connection.conn.SetWriteDeadline(time.Now().Add(connection.timeout))
+	}
+
+	if !connection.datagram {
+		writer := connection.conn.Write
+
+		if connection.gzip != nil {
+			writer = connection.gzip.Write
+		}
+
+		if _, err := writer(bytes.Join(lines, nil)); err != nil {
+			return err
+		}
+
+		if connection.gzip != nil {
+			return connection.gzip.Flush()
+		}
+
+		return nil
+	}
+
+	for _, line := range lines {
+		if connection.compress {
+			var buff bytes.Buffer
+
+			writer := gzip.NewWriter(&buff)
+			writer.Write(line)
+			writer.Close()
+
+			line = buff.Bytes()
+		}
+
+		if _, err := connection.conn.Write(line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (connection *connection) close() error {
+	if connection.gzip != nil {
+		connection.gzip.Close()
+	}
+
+	return connection.conn.Close()
+}
+
+// newTLSConfig load tls section, nil is returned if tls is not enabled
+func newTLSConfig(config scf4go.Config, address string) (*tls.Config, error) {
+	if !config.Get("tls", "enabled").Bool(false) {
+		return nil, nil
+	}
+
+	serverName, _, err := net.SplitHostPort(address)
+
+	if err != nil {
+		serverName = address
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         config.Get("tls", "server_name").String(serverName),
+		InsecureSkipVerify: config.Get("tls", "insecure_skip_verify").Bool(false),
+	}
+
+	if ca := config.Get("tls", "ca").String(""); ca != "" {
+		buff, err := ioutil.ReadFile(ca)
+
+		if err != nil {
+			return nil, errors.Wrap(err, "read tls ca %s error", ca)
+		}
+
+		pool := x509.NewCertPool()
+
+		if !pool.AppendCertsFromPEM(buff) {
+			return nil, errors.Wrap(slf4go.ErrArgs, "invalid tls ca %s", ca)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	cert := config.Get("tls", "cert").String("")
+	key := config.Get("tls", "key").String("")
+
+	if cert != "" || key != "" {
+		certificate, err := tls.LoadX509KeyPair(cert, key)
+
+		if err != nil {
+			return nil, errors.Wrap(err, "load tls client certificate %s error", cert)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,326 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/scf4go"
+	"github.com/libs4go/slf4go"
+	"github.com/libs4go/slf4go/internal/queue"
+)
+
+// networkImpl send newline delimited entries to remote collector, entries are buffered
+// during outages and sent by a background loop. Delivery is at-least-once: a batch failed
+// after a partial stream write is resent as a whole, so the collector may receive some lines twice
+type networkImpl struct {
+	mutex      sync.Mutex
+	Network    string        `json:"network"`
+	Address    string        `json:"address"`
+	Compress   bool          `json:"compress"`
+	Timeout    time.Duration `json:"timeout"`
+	BackoffMin time.Duration `json:"backoff_min"`
+	BackoffMax time.Duration `json:"backoff_max"`
+	BufferSize int64         `json:"buffer_size"`
+	Overflow   string        `json:"overflow"`
+	BatchSize  int           `json:"batch_size"`
+	formatter  slf4go.Formatter
+	dialer     *dialer
+	generation int          // increased by Config, the send loop reconnects if changed
+	queue      *queue.Queue // formatted lines
+	failed     uint64
+	written    uint64
+	stop       chan struct{} // abort sending after Close timeout
+	exited     chan struct{}
+	initOnce   sync.Once
+}
+
+func newNetwork(network string) *networkImpl {
+	impl := &networkImpl{
+		Network:    network,
+		Timeout:    5 * time.Second,
+		BackoffMin: 100 * time.Millisecond,
+		BackoffMax: 30 * time.Second,
+		BufferSize: 1024 * 1024,
+		Overflow:   queue.DropOldest,
+		BatchSize:  100,
+		formatter:  slf4go.JSONFormatter,
+		stop:       make(chan struct{}),
+		exited:     make(chan struct{}),
+	}
+
+	impl.dialer = &dialer{network: network, timeout: impl.Timeout}
+	impl.queue = queue.New(impl.queueOptions())
+
+	return impl
+}
+
+// queueOptions must be called with lock held
+func (network *networkImpl) queueOptions() queue.Options {
+	return queue.Options{
+		MaxBytes:  network.BufferSize,
+		Overflow:  network.Overflow,
+		BatchSize: network.BatchSize,
+	}
+}
+
+func (network *networkImpl) Send(entry *slf4go.EventEntry) {
+	network.start()
+
+	network.mutex.Lock()
+	buff, err := network.formatter.Format(entry)
+
+	if err != nil {
+		network.failed++
+		network.mutex.Unlock()
+		println(fmt.Sprintf("format event entry error %s", err))
+		return
+	}
+
+	network.mutex.Unlock()
+
+	network.queue.Push(append(buff, '\n'))
+}
+
+// backoff wait before next connect, return false if sending is aborted
+func (network *networkImpl) backoff(delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-network.stop:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (network *networkImpl) sendLoop() {
+	defer close(network.exited)
+
+	var conn *connection
+	var generation int
+	var delay time.Duration
+
+	defer func() {
+		if conn != nil {
+			conn.close()
+		}
+	}()
+
+	for {
+		batch := network.queue.Take()
+
+		if batch == nil {
+			return
+		}
+
+		network.mutex.Lock()
+		dialer := network.dialer
+
+		if conn != nil && generation != network.generation {
+			conn.close()
+			conn = nil
+		}
+
+		generation = network.generation
+		network.mutex.Unlock()
+
+		var err error
+
+		if conn == nil {
+			conn, err = dialer.dial()
+		}
+
+		if err == nil {
+			if err = conn.write(batch); err != nil {
+				conn.close()
+				conn = nil
+			}
+		}
+
+		if err != nil {
+			// lines of a partially written batch may already be received, they are sent again
+			network.queue.Requeue(batch)
+
+			network.mutex.Lock()
+
+			// the error is reported once per outage
+			if delay == 0 {
+				println(fmt.Sprintf("send to %s %s error %s", dialer.network, dialer.address, err))
+				delay = network.BackoffMin
+			} else if delay *= 2; delay > network.BackoffMax {
+				delay = network.BackoffMax
+			}
+
+			network.mutex.Unlock()
+
+			if !network.backoff(delay) {
+				// Close timeout, buffered lines are dropped
+				network.queue.Abort()
+				return
+			}
+
+			continue
+		}
+
+		delay = 0
+
+		network.mutex.Lock()
+
+		for _, line := range batch {
+			network.written += uint64(len(line))
+		}
+
+		network.mutex.Unlock()
+
+		network.queue.Done()
+	}
+}
+
+func (network *networkImpl) start() {
+	network.initOnce.Do(func() {
+		go network.sendLoop()
+	})
+}
+
+func (network *networkImpl) Stats() slf4go.BackendStats {
+	stats := network.queue.Stats()
+
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	stats.Written = network.written
+	stats.Failed = network.failed
+
+	return stats
+}
+
+// Flush wait until all events accepted before calling Flush are sent or dropped, the wait is
+// limited to timeout if ctx has no deadline, so Factory.Sync returns during outages
+func (network *networkImpl) Flush(ctx context.Context) error {
+	network.start()
+
+	if _, ok := ctx.Deadline(); !ok {
+		network.mutex.Lock()
+		timeout := network.Timeout
+		network.mutex.Unlock()
+
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	return network.queue.Flush(ctx)
+}
+
+func (network *networkImpl) Sync() {
+	if err := network.Flush(context.Background()); err != nil {
+		println(fmt.Sprintf("sync network backend error: %s", err))
+	}
+}
+
+// Close stop accepting events and drain the buffer, buffered events are dropped if ctx done
+func (network *networkImpl) Close(ctx context.Context) error {
+	network.start()
+
+	network.queue.Close()
+
+	select {
+	case <-network.exited:
+		return nil
+	case <-ctx.Done():
+	}
+
+	network.mutex.Lock()
+
+	select {
+	case <-network.stop:
+	default:
+		close(network.stop)
+	}
+
+	network.mutex.Unlock()
+
+	return errors.Wrap(ctx.Err(), "drain network events error")
+}
+
+func (network *networkImpl) Config(config scf4go.Config) error {
+	formatter, err := slf4go.NewFormatter(config, "json")
+
+	if err != nil {
+		return err
+	}
+
+	network.mutex.Lock()
+	networkType := network.Network
+	network.mutex.Unlock()
+
+	address := config.Get("address").String("")
+
+	if address == "" {
+		return errors.Wrap(slf4go.ErrArgs, "%s backend address is required", networkType)
+	}
+
+	bufferSize, err := slf4go.ConfigSize(config.Get("buffer_size"), 1024*1024)
+
+	if err != nil {
+		return err
+	}
+
+	overflow := strings.ToLower(config.Get("overflow").String(queue.DropOldest))
+
+	switch overflow {
+	case queue.DropNewest, queue.DropOldest:
+	default:
+		return errors.Wrap(slf4go.ErrArgs, "unknown network overflow policy %s", overflow)
+	}
+
+	tlsConfig, err := newTLSConfig(config, address)
+
+	if err != nil {
+		return err
+	}
+
+	if tlsConfig != nil && datagram(networkType) {
+		return errors.Wrap(slf4go.ErrArgs, "tls is not supported by %s", networkType)
+	}
+
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	network.Address = address
+	network.Compress = config.Get("compress").Bool(false)
+	network.Timeout = config.Get("timeout").Duration(5 * time.Second)
+	network.BackoffMin = config.Get("backoff_min").Duration(100 * time.Millisecond)
+	network.BackoffMax = config.Get("backoff_max").Duration(30 * time.Second)
+	network.BufferSize = bufferSize
+	network.Overflow = overflow
+	network.BatchSize = config.Get("batch_size").Int(100)
+	network.formatter = formatter
+	network.dialer = &dialer{
+		network:  networkType,
+		address:  address,
+		timeout:  network.Timeout,
+		compress: network.Compress,
+		tls:      tlsConfig,
+	}
+	network.generation++
+	network.queue.SetOptions(network.queueOptions())
+
+	return nil
+}
+
+func init() {
+	for _, networkType := range []string{"tcp", "udp", "unix"} {
+		networkType := networkType
+
+		slf4go.RegisterBackendType(networkType, func(name string) slf4go.Backend {
+			return newNetwork(networkType)
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/libs4go/slf4go"
+	"github.com/libs4go/slf4go/slf4gotest"
+	"github.com/stretchr/testify/require"
+)
+
+func newEntry(i int) *slf4go.EventEntry {
+	return slf4gotest.Entry(slf4go.INFO, fmt.Sprintf("message %d", i), "id", i)
+}
+
+// readLines decode json lines of reader into lines
+func readLines(reader io.Reader, lines chan map[string]interface{}) {
+	scanner := bufio.NewScanner(reader)
+
+	for scanner.Scan() {
+		var line map[string]interface{}
+
+		if json.Unmarshal(scanner.Bytes(), &line) == nil {
+			lines <- line
+		}
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	address := listener.Addr().String()
+
+	// entries are buffered while the collector is down
+	listener.Close()
+
+	network := newNetwork("tcp")
+
+	err = network.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+address: %s
+backoff_min: 10ms
+backoff_max: 50ms
+batch_size: 3
+`, address)))
+
+	require.NoError(t, err)
+
+	defer network.Close(context.Background())
+
+	for i := 0; i < 10; i++ {
+		network.Send(newEntry(i))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	require.Error(t, network.Flush(ctx))
+	cancel()
+
+	require.Equal(t, 10, network.Stats().Depth)
+
+	listener, err = net.Listen("tcp", address)
+	require.NoError(t, err)
+
+	defer listener.Close()
+
+	lines := make(chan map[string]interface{}, 100)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+
+			if err != nil {
+				return
+			}
+
+			go readLines(conn, lines)
+		}
+	}()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	require.NoError(t, network.Flush(ctx))
+	cancel()
+
+	for i := 0; i < 10; i++ {
+		line := <-lines
+		require.Equal(t, fmt.Sprintf("message %d", i), line["@m"])
+	}
+
+	stats := network.Stats()
+	require.Equal(t, uint64(10), stats.Enqueued)
+	require.Zero(t, stats.Dropped)
+	require.Zero(t, stats.Depth)
+}
+
+func TestOverflow(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	listener.Close()
+
+	network := newNetwork("tcp")
+
+	err = network.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+address: %s
+buffer_size: 1KB
+backoff_min: 1h
+`, listener.Addr())))
+
+	require.NoError(t, err)
+
+	for i := 0; i < 200; i++ {
+		network.Send(newEntry(i))
+	}
+
+	require.True(t, network.queue.Bytes() <= 1024)
+	require.True(t, network.Stats().Dropped > 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	require.Error(t, network.Close(ctx))
+	cancel()
+
+	<-network.exited
+
+	stats := network.Stats()
+	require.Zero(t, stats.Depth)
+	require.Equal(t, uint64(200), stats.Dropped)
+
+	err = newNetwork("tcp").Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+address: %s
+overflow: block
+`, listener.Addr())))
+
+	require.Error(t, err)
+}
+
+func TestSyncOutage(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	listener.Close()
+
+	network := newNetwork("tcp")
+
+	err = network.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+address: %s
+timeout: 100ms
+backoff_min: 1h
+`, listener.Addr())))
+
+	require.NoError(t, err)
+
+	factory := slf4go.New()
+	factory.RegisterBackend("tcp", network)
+	factory.ConfigDefault("tcp", slf4go.INFO)
+
+	factory.Get("test").I("lost")
+
+	// Factory.Sync flushes without deadline, the wait is limited by timeout
+	done := make(chan struct{})
+
+	go func() {
+		factory.Sync()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "sync blocked during outage")
+	}
+
+	require.Equal(t, 1, network.Stats().Depth)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	network.Close(ctx)
+	cancel()
+}
+
+func TestUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	network := newNetwork("udp")
+
+	err = network.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+address: %s
+compress: true
+`, conn.LocalAddr())))
+
+	require.NoError(t, err)
+
+	defer network.Close(context.Background())
+
+	network.Send(newEntry(1))
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	buff := make([]byte, 64*1024)
+
+	n, _, err := conn.ReadFrom(buff)
+	require.NoError(t, err)
+
+	reader, err := gzip.NewReader(bytes.NewReader(buff[:n]))
+	require.NoError(t, err)
+
+	data, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+
+	var line map[string]interface{}
+
+	require.NoError(t, json.Unmarshal(data, &line))
+	require.Equal(t, "message 1", line["@m"])
+}
+
+// writeCert create certificate signed by parent into dir, the certificate is self-signed if parent is nil
+func writeCert(t *testing.T, dir, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, server bool) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	if server {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+
+	if parent == nil {
+		template.ExtKeyUsage = nil
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent = template
+		parentKey = key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name+".pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+
+	return cert, key
+}
+
+func TestTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slf4go")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	ca, caKey := writeCert(t, dir, "ca", nil, nil, false)
+	writeCert(t, dir, "server", ca, caKey, true)
+	writeCert(t, dir, "client", ca, caKey, false)
+
+	serverCert, err := tls.LoadX509KeyPair(filepath.Join(dir, "server.pem"), filepath.Join(dir, "server.key"))
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+
+	require.NoError(t, err)
+
+	defer listener.Close()
+
+	lines := make(chan map[string]interface{}, 100)
+
+	go func() {
+		conn, err := listener.Accept()
+
+		if err != nil {
+			return
+		}
+
+		reader, err := gzip.NewReader(conn)
+
+		if err != nil {
+			return
+		}
+
+		readLines(reader, lines)
+	}()
+
+	network := newNetwork("tcp")
+
+	err = network.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+address: %s
+compress: true
+tls:
+  enabled: true
+  ca: %s
+  cert: %s
+  key: %s
+`, listener.Addr(), filepath.Join(dir, "ca.pem"), filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key"))))
+
+	require.NoError(t, err)
+
+	defer network.Close(context.Background())
+
+	for i := 0; i < 3; i++ {
+		network.Send(newEntry(i))
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case line := <-lines:
+			require.Equal(t, fmt.Sprintf("message %d", i), line["@m"])
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "tls lines not received")
+		}
+	}
+
+	err = newNetwork("udp").Config(slf4gotest.LoadConfig(t, `
+address: 127.0.0.1:514
+tls:
+  enabled: true
+`))
+
+	require.Error(t, err)
+}
@@ -0,0 +1,185 @@
+package syslog
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/slf4go"
+)
+
+// message formats
+const (
+	formatRFC5424 = "rfc5424"
+	formatRFC3164 = "rfc3164"
+)
+
+// facilities of RFC 5424
+var facilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+func parseFacility(name string) (int, error) {
+	facility, ok := facilities[strings.ToLower(name)]
+
+	if !ok {
+		return 0, errors.Wrap(slf4go.ErrArgs, "unknown syslog facility %s", name)
+	}
+
+	return facility, nil
+}
+
+// severity map level to syslog severity
+func severity(level slf4go.Level) int {
+	switch level {
+	case slf4go.ERROR:
+		return 3 // error
+	case slf4go.WARN:
+		return 4 // warning
+	case slf4go.INFO:
+		return 6 // informational
+	}
+
+	return 7 // debug
+}
+
+// header keep printable US-ASCII of header field, '-' is the nil value
+func header(value string, max int) string {
+	var buff strings.Builder
+
+	for i := 0; i < len(value) && buff.Len() < max; i++ {
+		if value[i] > ' ' && value[i] < 127 {
+			buff.WriteByte(value[i])
+		}
+	}
+
+	if buff.Len() == 0 {
+		return "-"
+	}
+
+	return buff.String()
+}
+
+// sdName keep characters allowed in SD-NAME
+func sdName(value string) string {
+	var buff strings.Builder
+
+	for i := 0; i < len(value) && buff.Len() < 32; i++ {
+		switch c := value[i]; {
+		case c <= ' ' || c >= 127 || c == '=' || c == ']' || c == '"':
+			buff.WriteByte('_')
+		default:
+			buff.WriteByte(c)
+		}
+	}
+
+	return buff.String()
+}
+
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// structuredData render attributes as one SD-ELEMENT
+func structuredData(id string, attrs map[string]interface{}) string {
+	if len(attrs) == 0 {
+		return "-"
+	}
+
+	keys := make([]string, 0, len(attrs))
+
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.TrimPrefix(keys[i], "@") < strings.TrimPrefix(keys[j], "@")
+	})
+
+	var buff strings.Builder
+
+	buff.WriteString("[")
+	buff.WriteString(id)
+
+	for _, key := range keys {
+		name := sdName(strings.TrimPrefix(key, "@"))
+
+		if name == "" {
+			continue
+		}
+
+		buff.WriteString(" ")
+		buff.WriteString(name)
+		buff.WriteString(`="`)
+		buff.WriteString(sdEscaper.Replace(slf4go.AttrString(attrs[key])))
+		buff.WriteString(`"`)
+	}
+
+	buff.WriteString("]")
+
+	return buff.String()
+}
+
+// encoder build syslog messages
+type encoder struct {
+	format   string
+	facility int
+	hostname string
+	appName  string
+	procID   string
+	sdID     string
+}
+
+func (encoder *encoder) priority(level slf4go.Level) string {
+	return "<" + strconv.Itoa(encoder.facility*8+severity(level)) + ">"
+}
+
+func (encoder *encoder) encode(entry *slf4go.EventEntry, message string) string {
+	timestamp := entry.Timestamp
+
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	if encoder.format == formatRFC3164 {
+		return fmt.Sprintf("%s%s %s %s[%s]: %s",
+			encoder.priority(entry.Level),
+			timestamp.Format(time.Stamp),
+			encoder.hostname,
+			header(encoder.appName, 32), // TAG is at most 32 characters
+			encoder.procID,
+			message,
+		)
+	}
+
+	return fmt.Sprintf("%s1 %s %s %s %s %s %s %s",
+		encoder.priority(entry.Level),
+		timestamp.Format("2006-01-02T15:04:05.000000Z07:00"),
+		encoder.hostname,
+		encoder.appName,
+		encoder.procID,
+		header(entry.Source, 32),
+		structuredData(encoder.sdID, entry.Attrs),
+		message,
+	)
+}
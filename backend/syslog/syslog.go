@@ -0,0 +1,318 @@
+package syslog
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/scf4go"
+	"github.com/libs4go/slf4go"
+)
+
+// framing of stream transports, see RFC 6587
+const (
+	framingOctetCounting = "octet-counting"
+	framingNewline       = "newline"
+)
+
+var errSuspended = errors.New("reconnect suspended after dial failed")
+
+// defaultSDID structured data ID of attributes, 32473 is the enterprise number reserved for documentation
+const defaultSDID = "attrs@32473"
+
+func defaultAddress(network string) string {
+	switch network {
+	case "unix", "unixgram":
+		return "/dev/log"
+	}
+
+	return "localhost:514"
+}
+
+// datagram check if each write of network is one message
+func datagram(network string) bool {
+	switch network {
+	case "udp", "udp4", "udp6", "unixgram":
+		return true
+	}
+
+	return false
+}
+
+type syslogImpl struct {
+	written           uint64        // accessed atomically, keep 64-bit aligned
+	failed            uint64        // accessed atomically, keep 64-bit aligned
+	Network           string        `json:"network"`
+	Address           string        `json:"address"`
+	Framing           string        `json:"framing"`
+	Timeout           time.Duration `json:"timeout"`
+	ReconnectInterval time.Duration `json:"reconnect_interval"`
+	encoder           *encoder
+	format            slf4go.Formatter // formatter of MSG selected with 'format', the message is sent as is by default
+	mutex             sync.Mutex       // guard all fields except counters
+	conn              net.Conn         // nil if not connected
+	connNetwork       string           // network of conn, unix connects unixgram first
+	nextDial          time.Time        // connecting is suspended until nextDial after dial failed
+}
+
+func newSyslog() *syslogImpl {
+	hostname, err := os.Hostname()
+
+	if err != nil {
+		hostname = "localhost"
+	}
+
+	return &syslogImpl{
+		Network:           "udp",
+		Address:           defaultAddress("udp"),
+		Framing:           framingOctetCounting,
+		Timeout:           5 * time.Second,
+		ReconnectInterval: time.Second,
+		encoder: &encoder{
+			format:   formatRFC5424,
+			facility: facilities["user"],
+			hostname: header(hostname, 255),
+			appName:  header(filepath.Base(os.Args[0]), 48),
+			procID:   strconv.Itoa(os.Getpid()),
+			sdID:     defaultSDID,
+		},
+	}
+}
+
+func (syslog *syslogImpl) message(entry *slf4go.EventEntry) (string, bool) {
+	message := entry.Message
+
+	if syslog.format != nil {
+		buff, err := syslog.format.Format(entry)
+
+		if err != nil {
+			println(fmt.Sprintf("format event entry error %s", err))
+			return "", false
+		}
+
+		message = string(buff)
+	}
+
+	return syslog.encoder.encode(entry, message), true
+}
+
+// frame add framing of stream connection to message, must be called with mutex held
+func (syslog *syslogImpl) frame(message string) string {
+	if datagram(syslog.connNetwork) {
+		return message
+	}
+
+	if syslog.Framing == framingNewline {
+		// embedded newlines would split the message
+		return strings.Replace(message, "\n", " ", -1) + "\n"
+	}
+
+	return strconv.Itoa(len(message)) + " " + message
+}
+
+func (syslog *syslogImpl) Send(entry *slf4go.EventEntry) {
+	syslog.mutex.Lock()
+	defer syslog.mutex.Unlock()
+
+	message, ok := syslog.message(entry)
+
+	if !ok {
+		atomic.AddUint64(&syslog.failed, 1)
+		return
+	}
+
+	if err := syslog.write(message); err != nil {
+		// the dial error is reported once until reconnecting
+		if err != errSuspended {
+			println(fmt.Sprintf("send to syslog %s %s error %s", syslog.Network, syslog.Address, err))
+		}
+
+		atomic.AddUint64(&syslog.failed, 1)
+	}
+}
+
+// write send message, the message is resent once over a new connection if the write fails
+func (syslog *syslogImpl) write(message string) error {
+	var err error
+
+	for retry := 0; retry < 2; retry++ {
+		if err = syslog.connect(); err != nil {
+			return err
+		}
+
+		if syslog.Timeout > 0 {
+			syslog.conn.SetWriteDeadline(time.Now().Add(syslog.Timeout))
+		}
+
+		var n int
+
+		n, err = syslog.conn.Write([]byte(syslog.frame(message)))
+
+		atomic.AddUint64(&syslog.written, uint64(n))
+
+		if err == nil {
+			return nil
+		}
+
+		syslog.disconnect()
+
+		if n > 0 {
+			// partially written message can't be resent without corrupting the stream
+			return err
+		}
+	}
+
+	return err
+}
+
+// connect dial syslog server if not connected, must be called with mutex held
+func (syslog *syslogImpl) connect() error {
+	if syslog.conn != nil {
+		return nil
+	}
+
+	if time.Now().Before(syslog.nextDial) {
+		return errSuspended
+	}
+
+	network := syslog.Network
+
+	if network == "unix" {
+		// local syslog sockets like /dev/log are usually datagram sockets, same as log/syslog
+		network = "unixgram"
+	}
+
+	conn, err := net.DialTimeout(network, syslog.Address, syslog.Timeout)
+
+	if err != nil && network != syslog.Network {
+		network = syslog.Network
+		conn, err = net.DialTimeout(network, syslog.Address, syslog.Timeout)
+	}
+
+	if err != nil {
+		syslog.nextDial = time.Now().Add(syslog.ReconnectInterval)
+		return err
+	}
+
+	syslog.conn = conn
+	syslog.connNetwork = network
+
+	return nil
+}
+
+// disconnect must be called with mutex held
+func (syslog *syslogImpl) disconnect() error {
+	if syslog.conn == nil {
+		return nil
+	}
+
+	err := syslog.conn.Close()
+	syslog.conn = nil
+
+	return err
+}
+
+func (syslog *syslogImpl) Sync() {
+}
+
+// Close close the connection
+func (syslog *syslogImpl) Close(ctx context.Context) error {
+	syslog.mutex.Lock()
+	defer syslog.mutex.Unlock()
+
+	return syslog.disconnect()
+}
+
+func (syslog *syslogImpl) Stats() slf4go.BackendStats {
+	return slf4go.BackendStats{
+		Written: atomic.LoadUint64(&syslog.written),
+		Failed:  atomic.LoadUint64(&syslog.failed),
+	}
+}
+
+func (syslog *syslogImpl) Config(config scf4go.Config) error {
+	network := config.Get("network").String("udp")
+
+	switch network {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6", "unix", "unixgram":
+	default:
+		return errors.Wrap(slf4go.ErrArgs, "unknown syslog network %s", network)
+	}
+
+	framing := config.Get("framing").String(framingOctetCounting)
+
+	switch framing {
+	case framingOctetCounting, framingNewline:
+	default:
+		return errors.Wrap(slf4go.ErrArgs, "unknown syslog framing %s", framing)
+	}
+
+	format := config.Get("protocol").String(formatRFC5424)
+
+	switch format {
+	case formatRFC5424, formatRFC3164:
+	default:
+		return errors.Wrap(slf4go.ErrArgs, "unknown syslog protocol %s", format)
+	}
+
+	facility, err := parseFacility(config.Get("facility").String("user"))
+
+	if err != nil {
+		return err
+	}
+
+	hostname, err := os.Hostname()
+
+	if err != nil {
+		hostname = "localhost"
+	}
+
+	encoder := &encoder{
+		format:   format,
+		facility: facility,
+		hostname: header(config.Get("hostname").String(hostname), 255),
+		appName:  header(config.Get("app_name").String(filepath.Base(os.Args[0])), 48),
+		procID:   strconv.Itoa(os.Getpid()),
+		sdID:     sdName(config.Get("sd_id").String(defaultSDID)),
+	}
+
+	var messageFormat slf4go.Formatter
+
+	if config.Get("format").String("") != "" {
+		messageFormat, err = slf4go.NewFormatter(config, "")
+
+		if err != nil {
+			return err
+		}
+	}
+
+	syslog.mutex.Lock()
+	defer syslog.mutex.Unlock()
+
+	syslog.disconnect()
+
+	syslog.Network = network
+	syslog.Address = config.Get("address").String(defaultAddress(network))
+	syslog.Framing = framing
+	syslog.Timeout = config.Get("timeout").Duration(5 * time.Second)
+	syslog.ReconnectInterval = config.Get("reconnect_interval").Duration(time.Second)
+	syslog.encoder = encoder
+	syslog.format = messageFormat
+	syslog.nextDial = time.Time{}
+
+	return nil
+}
+
+func init() {
+	slf4go.RegisterBackendType("syslog", func(name string) slf4go.Backend {
+		return newSyslog()
+	})
+}
@@ -0,0 +1,263 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libs4go/slf4go"
+	"github.com/libs4go/slf4go/slf4gotest"
+	"github.com/stretchr/testify/require"
+)
+
+func newEntry(message string) *slf4go.EventEntry {
+	entry := slf4gotest.Entry(slf4go.ERROR, message, "user", "a\"b]", "id", 1)
+	entry.Timestamp = time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC)
+	entry.Source = "auth"
+
+	return entry
+}
+
+// readFrames read octet-counted frames of conn
+func readFrames(conn net.Conn, frames chan string) {
+	reader := bufio.NewReader(conn)
+
+	for {
+		size, err := reader.ReadString(' ')
+
+		if err != nil {
+			return
+		}
+
+		length, err := strconv.Atoi(strings.TrimSpace(size))
+
+		if err != nil {
+			return
+		}
+
+		frame := make([]byte, length)
+
+		if _, err := io.ReadFull(reader, frame); err != nil {
+			return
+		}
+
+		frames <- string(frame)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	encoder := &encoder{
+		format:   formatRFC5424,
+		facility: facilities["local0"],
+		hostname: "host",
+		appName:  "app",
+		procID:   "42",
+		sdID:     defaultSDID,
+	}
+
+	require.Equal(t,
+		`<131>1 2020-01-02T03:04:05.000006Z host app 42 auth [attrs@32473 id="1" user="a\"b\]"] login failed`,
+		encoder.encode(newEntry("login failed"), "login failed"))
+
+	encoder.format = formatRFC3164
+
+	require.Equal(t, "<131>Jan  2 03:04:05 host app[42]: login failed", encoder.encode(newEntry("login failed"), "login failed"))
+
+	encoder.appName = strings.Repeat("a", 48)
+	require.Equal(t, "<131>Jan  2 03:04:05 host "+strings.Repeat("a", 32)+"[42]: login failed", encoder.encode(newEntry("login failed"), "login failed"))
+
+	require.Equal(t, "-", header(" \t", 10))
+	require.Equal(t, "-", structuredData(defaultSDID, nil))
+
+	_, err := parseFacility("unknown")
+	require.Error(t, err)
+}
+
+func TestUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	syslog := newSyslog()
+
+	err = syslog.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+network: udp
+address: %s
+facility: daemon
+app_name: test
+hostname: host
+`, conn.LocalAddr())))
+
+	require.NoError(t, err)
+
+	defer syslog.Close(context.Background())
+
+	syslog.Send(newEntry("hello"))
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	buff := make([]byte, 1024)
+
+	n, _, err := conn.ReadFrom(buff)
+	require.NoError(t, err)
+
+	require.Equal(t,
+		fmt.Sprintf(`<27>1 2020-01-02T03:04:05.000006Z host test %d auth [attrs@32473 id="1" user="a\"b\]"] hello`, os.Getpid()),
+		string(buff[:n]))
+
+	require.Zero(t, syslog.Stats().Failed)
+}
+
+func TestTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer listener.Close()
+
+	frames := make(chan string, 100)
+
+	go func() {
+		for first := true; ; first = false {
+			conn, err := listener.Accept()
+
+			if err != nil {
+				return
+			}
+
+			if first {
+				// drop the first connection to force reconnect
+				conn.Close()
+				continue
+			}
+
+			go readFrames(conn, frames)
+		}
+	}()
+
+	syslog := newSyslog()
+
+	err = syslog.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+network: tcp
+address: %s
+reconnect_interval: 10ms
+format: text
+template: "{{.Message}} id={{attr . \"id\"}}"
+`, listener.Addr())))
+
+	require.NoError(t, err)
+
+	defer syslog.Close(context.Background())
+
+	// writes into the dropped connection may succeed before the reset is noticed
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		require.True(t, time.Now().Before(deadline), "reconnect timeout")
+
+		syslog.Send(newEntry("multi\nline"))
+
+		select {
+		case frame := <-frames:
+			require.True(t, strings.HasSuffix(frame, "] multi\nline id=1"), frame)
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func TestUnix(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets are not supported")
+	}
+
+	dir, err := ioutil.TempDir("", "slf4go")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "syslog.sock")
+
+	syslog := newSyslog()
+
+	err = syslog.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+network: unix
+address: %s
+protocol: rfc3164
+framing: newline
+reconnect_interval: 1h
+app_name: test
+hostname: host
+`, path)))
+
+	require.NoError(t, err)
+
+	defer syslog.Close(context.Background())
+
+	// dial fails and reconnecting is suspended
+	syslog.Send(newEntry("lost"))
+	syslog.Send(newEntry("lost"))
+	require.Equal(t, uint64(2), syslog.Stats().Failed)
+
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+
+	defer listener.Close()
+
+	syslog.mutex.Lock()
+	syslog.nextDial = time.Time{}
+	syslog.mutex.Unlock()
+
+	syslog.Send(newEntry("multi\nline"))
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("<11>Jan  2 03:04:05 host test[%d]: multi line\n", os.Getpid()), line)
+
+	// datagram sockets like /dev/log are connected first and messages are not framed
+	path = filepath.Join(dir, "syslog.dgram")
+
+	packetConn, err := net.ListenPacket("unixgram", path)
+	require.NoError(t, err)
+
+	defer packetConn.Close()
+
+	syslog = newSyslog()
+
+	err = syslog.Config(slf4gotest.LoadConfig(t, fmt.Sprintf(`
+network: unix
+address: %s
+protocol: rfc3164
+app_name: test
+hostname: host
+`, path)))
+
+	require.NoError(t, err)
+
+	defer syslog.Close(context.Background())
+
+	syslog.Send(newEntry("datagram"))
+
+	packetConn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	buff := make([]byte, 1024)
+
+	n, _, err := packetConn.ReadFrom(buff)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("<11>Jan  2 03:04:05 host test[%d]: datagram", os.Getpid()), string(buff[:n]))
+}
@@ -5,6 +5,15 @@ import (
 	"strings"
 )
 
+func trimFrame(frame runtime.Frame) runtime.Frame {
+	if index := strings.Index(frame.File, "src"); index != -1 {
+		// trim GOPATH or GOROOT prifix
+		frame.File = string(frame.File[index+4:])
+	}
+
+	return frame
+}
+
 func getCallFrame() runtime.Frame {
 	pcs := make([]uintptr, 2)
 
@@ -14,10 +23,32 @@ func getCallFrame() runtime.Frame {
 
 	frame, _ := frames.Next()
 
-	if index := strings.Index(frame.File, "src"); index != -1 {
-		// trim GOPATH or GOROOT prifix
-		frame.File = string(frame.File[index+4:])
+	return trimFrame(frame)
+}
+
+// CallerFrame get the first frame above the function calling CallerFrame whose function name
+// is not matched by skip, adapters use it to find the code calling the adapted logging API
+func CallerFrame(skip func(function string) bool) runtime.Frame {
+	pcs := make([]uintptr, 32)
+
+	count := runtime.Callers(3, pcs)
+
+	frames := runtime.CallersFrames(pcs[:count])
+
+	for {
+		frame, more := frames.Next()
+
+		if !skip(frame.Function) || !more {
+			return trimFrame(frame)
+		}
 	}
+}
 
-	return frame
+// PCFrame get call frame of program counter returned by runtime.Callers
+func PCFrame(pc uintptr) runtime.Frame {
+	frames := runtime.CallersFrames([]uintptr{pc})
+
+	frame, _ := frames.Next()
+
+	return trimFrame(frame)
 }
@@ -1,126 +1,491 @@
 package cached
 
 import (
+	"context"
+	"fmt"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/libs4go/errors"
 	"github.com/libs4go/scf4go"
 	"github.com/libs4go/slf4go"
 )
 
+// overflow policies applied when the queue is full
+const (
+	overflowBlock      = "block"       // block caller until queue has room
+	overflowTimeout    = "timeout"     // block caller at most timeout, then drop the new event
+	overflowDropNewest = "drop_newest" // drop the new event
+	overflowDropOldest = "drop_oldest" // drop the oldest queued event
+	overflowDropLevel  = "drop_level"  // drop events below drop_level, block for the others
+)
+
 type cachedBackend struct {
 	sync.Mutex
-	sync.Once
-	syncCond *sync.Cond
-	backend  slf4go.Backend
-	cached   chan *slf4go.EventEntry
-	closed   bool
-	exitLoop bool
-	filter   *cachedFilter
-	initOnce sync.Once
+	backend    slf4go.Backend
+	filter     *cachedFilter
+	queue      []*slf4go.EventEntry
+	queueBytes int           // estimated bytes of queued events
+	queuedAt   time.Time     // time when the queue became non-empty
+	changed    chan struct{} // closed and recreated when queue state changed
+	waiters    int
+	flushing   int    // flush callers waiting, batches are sent without waiting latency
+	pending    int    // events taken by send loop but not sent yet
+	enqueued   uint64 // events accepted
+	processed  uint64 // events sent or evicted from queue
+	dropped    uint64
+	reported   uint64 // dropped events already reported
+	lastReport time.Time
+	closed     bool
+	exited     chan struct{}
+	initOnce   sync.Once
 }
 
 func (cached *cachedFilter) newCachedBackend(backend slf4go.Backend) slf4go.Backend {
 
-	wrapper := &cachedBackend{
+	return &cachedBackend{
 		backend: backend,
 		filter:  cached,
+		changed: make(chan struct{}),
+		exited:  make(chan struct{}),
 	}
+}
 
-	wrapper.syncCond = sync.NewCond(wrapper)
+// wait release lock until queue state changed or ctx done, must be called with lock held
+func (cached *cachedBackend) wait(ctx context.Context) error {
+	changed := cached.changed
+	cached.waiters++
+	cached.Unlock()
 
-	return wrapper
-}
+	var err error
+
+	select {
+	case <-changed:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
 
-func (cached *cachedBackend) notifyExit() {
 	cached.Lock()
-	defer cached.Unlock()
+	cached.waiters--
 
-	cached.exitLoop = true
-	cached.syncCond.Broadcast()
+	return err
+}
 
+// notify wakeup all waiters, must be called with lock held
+func (cached *cachedBackend) notify() {
+	if cached.waiters == 0 {
+		return
+	}
+
+	close(cached.changed)
+	cached.changed = make(chan struct{})
 }
 
-func (cached *cachedBackend) sendLoop() {
-	for {
-		if evt, ok := <-cached.cached; ok {
-			cached.backend.Send(evt)
+// batchReady check if queued events should be sent now, must be called with lock held
+func (cached *cachedBackend) batchReady() bool {
+	if len(cached.queue) == 0 {
+		return false
+	}
+
+	filter := cached.filter
+
+	if cached.closed || cached.flushing > 0 || filter.batchLatency <= 0 {
+		return true
+	}
+
+	if filter.batchSize > 0 && len(cached.queue) >= filter.batchSize {
+		return true
+	}
+
+	if filter.batchBytes > 0 && cached.queueBytes >= filter.batchBytes {
+		return true
+	}
+
+	return !time.Now().Before(cached.queuedAt.Add(filter.batchLatency))
+}
+
+// reportDue check if dropped events report should be sent now, must be called with lock held
+func (cached *cachedBackend) reportDue() bool {
+	if cached.dropped == cached.reported || cached.filter.reportInterval <= 0 {
+		return false
+	}
+
+	return cached.closed || !time.Now().Before(cached.lastReport.Add(cached.filter.reportInterval))
+}
+
+// deadline get the earliest time the send loop must wake up without queue state changed,
+// must be called with lock held
+func (cached *cachedBackend) deadline() (time.Time, bool) {
+	var deadline time.Time
+	var ok bool
+
+	if cached.dropped != cached.reported && cached.filter.reportInterval > 0 {
+		deadline = cached.lastReport.Add(cached.filter.reportInterval)
+		ok = true
+	}
+
+	if len(cached.queue) > 0 && cached.filter.batchLatency > 0 {
+		batchDeadline := cached.queuedAt.Add(cached.filter.batchLatency)
+
+		if !ok || batchDeadline.Before(deadline) {
+			deadline = batchDeadline
+			ok = true
+		}
+	}
+
+	return deadline, ok
+}
+
+// waitEvents wait until a batch is ready, closed or dropped events report is due, must be called with lock held
+func (cached *cachedBackend) waitEvents() {
+	for !cached.closed && !cached.batchReady() && !cached.reportDue() {
+
+		deadline, ok := cached.deadline()
+
+		if !ok {
+			cached.wait(context.Background())
 			continue
 		}
 
-		cached.notifyExit()
-		return
+		ctx, cancel := context.WithDeadline(context.Background(), deadline)
+		cached.wait(ctx)
+		cancel()
 	}
 }
 
-func (cached *cachedBackend) Config(config scf4go.Config) error {
-	return cached.backend.Config(config)
+// takeBatch remove a batch limited by batch_size and batch_bytes from the queue head,
+// must be called with lock held
+func (cached *cachedBackend) takeBatch() []*slf4go.EventEntry {
+	filter := cached.filter
+
+	count := 0
+	bytes := 0
+
+	for count < len(cached.queue) {
+		if filter.batchSize > 0 && count >= filter.batchSize {
+			break
+		}
+
+		size := entrySize(cached.queue[count])
+
+		if filter.batchBytes > 0 && count > 0 && bytes+size > filter.batchBytes {
+			break
+		}
+
+		bytes += size
+		count++
+	}
+
+	batch := make([]*slf4go.EventEntry, count)
+	copy(batch, cached.queue)
+
+	if count == len(cached.queue) {
+		cached.queue = nil
+	} else {
+		cached.queue = append(cached.queue[:0], cached.queue[count:]...)
+	}
+
+	cached.queueBytes -= bytes
+
+	return batch
 }
 
-func (cached *cachedBackend) createChan() {
-	cached.initOnce.Do(func() {
+const dropTemplate = "{@dropped} events dropped"
+
+// dropReport create dropped events report entry if it is due, must be called with lock held
+func (cached *cachedBackend) dropReport() *slf4go.EventEntry {
+	if !cached.reportDue() {
+		return nil
+	}
+
+	now := time.Now()
+
+	dropped := cached.dropped - cached.reported
+
+	cached.reported = cached.dropped
+	cached.lastReport = now
+
+	return &slf4go.EventEntry{
+		Timestamp: now,
+		Level:     slf4go.WARN,
+		Message:   fmt.Sprintf("%d events dropped", dropped),
+		Template:  dropTemplate,
+		EventType: slf4go.EventTypeOf(dropTemplate),
+		Attrs:     map[string]interface{}{"@dropped": dropped},
+		Source:    "slf4go",
+	}
+}
+
+func (cached *cachedBackend) sendLoop() {
+	defer close(cached.exited)
+
+	for {
+		cached.Lock()
+
+		cached.waitEvents()
+
+		events := cached.takeBatch()
+		cached.pending = len(events)
+		report := cached.dropReport()
+		cached.notify()
+		cached.Unlock()
+
+		if len(events) == 0 && report == nil {
+			return
+		}
+
+		if len(events) > 0 {
+			slf4go.SendBatch(cached.backend, events)
+		}
+
+		if report != nil {
+			slf4go.SendBatch(cached.backend, []*slf4go.EventEntry{report})
+		}
+
 		cached.Lock()
-		defer cached.Unlock()
-		cached.cached = make(chan *slf4go.EventEntry, cached.filter.cachedSize)
+		cached.processed += uint64(len(events))
+		cached.pending = 0
+		cached.notify()
+		cached.Unlock()
+	}
+}
+
+func (cached *cachedBackend) start() {
+	cached.initOnce.Do(func() {
 		go cached.sendLoop()
 	})
 }
 
-func (cached *cachedBackend) Send(entry *slf4go.EventEntry) {
+func (cached *cachedBackend) Config(config scf4go.Config) error {
+	return cached.backend.Config(config)
+}
 
-	if cached.closed {
-		return
+// entrySize estimate event entry encoded size
+func entrySize(entry *slf4go.EventEntry) int {
+	size := 128 + len(entry.Message) + len(entry.Source) + len(entry.File) + len(entry.Function)
+
+	for key, value := range entry.Attrs {
+		size += len(key) + 8
+
+		if s, ok := value.(string); ok {
+			size += len(s)
+		} else {
+			size += 16
+		}
+	}
+
+	return size
+}
+
+// evict drop queued event at index i, must be called with lock held
+func (cached *cachedBackend) evict(i int) {
+	cached.queueBytes -= entrySize(cached.queue[i])
+	copy(cached.queue[i:], cached.queue[i+1:])
+	cached.queue[len(cached.queue)-1] = nil
+	cached.queue = cached.queue[:len(cached.queue)-1]
+	cached.processed++
+	cached.dropped++
+}
+
+// block wait until queue has room, return false if ctx done or closed, must be called with lock held
+func (cached *cachedBackend) block(ctx context.Context) bool {
+	for !cached.closed && len(cached.queue) >= cached.filter.cachedSize {
+		if err := cached.wait(ctx); err != nil {
+			return false
+		}
 	}
 
-	cached.createChan()
+	return !cached.closed
+}
+
+// makeRoom apply overflow policy on full queue, return false if entry should be dropped,
+// must be called with lock held
+func (cached *cachedBackend) makeRoom(entry *slf4go.EventEntry) bool {
+	filter := cached.filter
 
-	defer func() {
-		if err := recover(); err != nil {
-			cached.closed = true
+	switch filter.overflow {
+	case overflowDropNewest:
+		return false
+	case overflowDropOldest:
+		cached.evict(0)
+		return true
+	case overflowDropLevel:
+		if entry.Level < filter.dropLevel && entry.Level != slf4go.ERROR {
+			return false
 		}
-	}()
 
-	cached.cached <- entry
+		for i, evt := range cached.queue {
+			if evt.Level < filter.dropLevel && evt.Level != slf4go.ERROR {
+				cached.evict(i)
+				return true
+			}
+		}
+	case overflowTimeout:
+		ctx, cancel := context.WithTimeout(context.Background(), filter.timeout)
+		defer cancel()
+
+		return cached.block(ctx)
+	}
+
+	return cached.block(context.Background())
 }
 
-func (cached *cachedBackend) Sync() {
+func (cached *cachedBackend) Send(entry *slf4go.EventEntry) {
+
+	cached.start()
 
 	cached.Lock()
 	defer cached.Unlock()
 
-	if cached.cached == nil {
+	if cached.closed || (len(cached.queue) >= cached.filter.cachedSize && !cached.makeRoom(entry)) {
+		cached.dropped++
+		cached.notify()
 		return
 	}
 
-	cached.Do(func() {
-		close(cached.cached)
-		cached.closed = true
-	})
+	if len(cached.queue) == 0 {
+		cached.queuedAt = time.Now()
+	}
 
-	if cached.exitLoop {
-		return
+	cached.queue = append(cached.queue, entry)
+	cached.queueBytes += entrySize(entry)
+	cached.enqueued++
+	cached.notify()
+}
+
+// Stats get queue statistics merged with wrapped backend statistics
+func (cached *cachedBackend) Stats() slf4go.BackendStats {
+	cached.Lock()
+	defer cached.Unlock()
+
+	stats := slf4go.BackendStats{
+		Enqueued: cached.enqueued,
+		Dropped:  cached.dropped,
+		Depth:    len(cached.queue) + cached.pending,
+	}
+
+	if reporter, ok := cached.backend.(slf4go.StatsBackend); ok {
+		stats = stats.Add(reporter.Stats())
 	}
 
-	cached.syncCond.Wait()
+	return stats
+}
+
+// Flush wait until all events enqueued before calling Flush are sent, then flush wrapped backend
+func (cached *cachedBackend) Flush(ctx context.Context) error {
+
+	cached.start()
+
+	cached.Lock()
+
+	target := cached.enqueued
+
+	cached.flushing++
+	cached.notify()
+
+	for cached.processed < target {
+		if err := cached.wait(ctx); err != nil {
+			cached.flushing--
+			cached.Unlock()
+			return errors.Wrap(err, "flush cached events(%d) error", target-cached.processed)
+		}
+	}
+
+	cached.flushing--
+	cached.Unlock()
+
+	return slf4go.FlushBackend(ctx, cached.backend)
+}
+
+// Close stop accepting events, drain the queue and close wrapped backend
+func (cached *cachedBackend) Close(ctx context.Context) error {
+
+	cached.start()
+
+	cached.Lock()
+	cached.closed = true
+	cached.notify()
+	cached.Unlock()
+
+	select {
+	case <-cached.exited:
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "drain cached events error")
+	}
+
+	return slf4go.CloseBackend(ctx, cached.backend)
+}
+
+func (cached *cachedBackend) Sync() {
+	if err := cached.Flush(context.Background()); err != nil {
+		println(fmt.Sprintf("sync cached backend error: %s", err))
+	}
 }
 
 type cachedFilter struct {
-	cachedSize int
+	name           string
+	cachedSize     int
+	overflow       string
+	timeout        time.Duration
+	dropLevel      slf4go.Level
+	reportInterval time.Duration
+	batchSize      int
+	batchBytes     int
+	batchLatency   time.Duration
 }
 
 func (cached *cachedFilter) Name() string {
-	return "cached"
+	return cached.name
 }
 
 func (cached *cachedFilter) Config(config scf4go.Config) {
 	cached.cachedSize = config.Get("size").Int(1000)
+	cached.overflow = strings.ToLower(config.Get("overflow").String(overflowBlock))
+	cached.timeout = config.Get("timeout").Duration(time.Millisecond * 100)
+	cached.reportInterval = config.Get("report_interval").Duration(time.Second * 10)
+	cached.batchSize = config.Get("batch_size").Int(100)
+	cached.batchBytes = config.Get("batch_bytes").Int(1024 * 1024)
+	cached.batchLatency = config.Get("batch_latency").Duration(0)
+
+	level, err := slf4go.ParseLevel(config.Get("drop_level").String("info"))
+
+	if err != nil {
+		println(fmt.Sprintf("cached filter drop_level error: %s", err))
+		level = slf4go.INFO
+	}
+
+	cached.dropLevel = level
+
+	switch cached.overflow {
+	case overflowBlock, overflowTimeout, overflowDropNewest, overflowDropOldest, overflowDropLevel:
+	default:
+		println(fmt.Sprintf("cached filter unknown overflow policy %s, using %s", cached.overflow, overflowBlock))
+		cached.overflow = overflowBlock
+	}
 }
 
 func (cached *cachedFilter) MakeChain(backend slf4go.Backend) slf4go.Backend {
 	return cached.newCachedBackend(backend)
 }
 
+func newCachedFilter(name string) *cachedFilter {
+	return &cachedFilter{
+		name:           name,
+		cachedSize:     1000,
+		overflow:       overflowBlock,
+		timeout:        time.Millisecond * 100,
+		dropLevel:      slf4go.INFO,
+		reportInterval: time.Second * 10,
+		batchSize:      100,
+		batchBytes:     1024 * 1024,
+	}
+}
+
 func init() {
-	slf4go.RegisterFilter(&cachedFilter{
-		cachedSize: 1000,
+	slf4go.RegisterFilterType("cached", func(name string) slf4go.Filter {
+		return newCachedFilter(name)
 	})
+
+	slf4go.RegisterFilter(newCachedFilter("cached"))
 }
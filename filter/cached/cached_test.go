@@ -1,12 +1,16 @@
 package cached
 
 import (
+	"context"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/libs4go/scf4go"
 	_ "github.com/libs4go/scf4go/codec"
 	"github.com/libs4go/scf4go/reader/file"
 	"github.com/libs4go/slf4go"
+	"github.com/stretchr/testify/require"
 
 	_ "github.com/libs4go/slf4go/backend/console"
 )
@@ -45,3 +49,240 @@ func BenchmarkWrite(t *testing.B) {
 	}
 
 }
+
+type mockBackend struct {
+	sync.Mutex
+	events  []*slf4go.EventEntry
+	flushed int
+	closed  bool
+}
+
+func (mock *mockBackend) Send(event *slf4go.EventEntry) {
+	mock.Lock()
+	defer mock.Unlock()
+	mock.events = append(mock.events, event)
+}
+
+func (mock *mockBackend) Sync() {
+	mock.Lock()
+	defer mock.Unlock()
+	mock.flushed++
+}
+
+func (mock *mockBackend) Close(ctx context.Context) error {
+	mock.Lock()
+	defer mock.Unlock()
+	mock.closed = true
+	return nil
+}
+
+func (mock *mockBackend) Config(config scf4go.Config) error {
+	return nil
+}
+
+func (mock *mockBackend) count() int {
+	mock.Lock()
+	defer mock.Unlock()
+	return len(mock.events)
+}
+
+func TestFlush(t *testing.T) {
+	mock := &mockBackend{}
+	backend := (&cachedFilter{cachedSize: 10}).MakeChain(mock)
+
+	for i := 0; i < 100; i++ {
+		backend.Send(&slf4go.EventEntry{Message: "test"})
+	}
+
+	require.NoError(t, slf4go.FlushBackend(context.Background(), backend))
+	require.Equal(t, 100, mock.count())
+	require.Equal(t, 1, mock.flushed)
+
+	backend.Sync()
+
+	backend.Send(&slf4go.EventEntry{Message: "after sync"})
+
+	require.NoError(t, slf4go.FlushBackend(context.Background(), backend))
+	require.Equal(t, 101, mock.count())
+
+	require.NoError(t, slf4go.CloseBackend(context.Background(), backend))
+	require.True(t, mock.closed)
+
+	backend.Send(&slf4go.EventEntry{Message: "after close"})
+	require.Equal(t, 101, mock.count())
+}
+
+type blockedBackend struct {
+	mockBackend
+	release chan struct{}
+}
+
+func (blocked *blockedBackend) Send(event *slf4go.EventEntry) {
+	<-blocked.release
+	blocked.mockBackend.Send(event)
+}
+
+func TestFlushDeadline(t *testing.T) {
+	blocked := &blockedBackend{release: make(chan struct{})}
+	backend := (&cachedFilter{cachedSize: 10}).MakeChain(blocked)
+
+	backend.Send(&slf4go.EventEntry{Message: "test"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	require.Error(t, slf4go.FlushBackend(ctx, backend))
+
+	close(blocked.release)
+
+	require.NoError(t, slf4go.CloseBackend(context.Background(), backend))
+	require.Equal(t, 1, blocked.count())
+}
+
+type gatedBackend struct {
+	mockBackend
+	entered chan struct{}
+	release chan struct{}
+}
+
+func newGatedBackend() *gatedBackend {
+	return &gatedBackend{
+		entered: make(chan struct{}, 1000),
+		release: make(chan struct{}),
+	}
+}
+
+func (gated *gatedBackend) Send(event *slf4go.EventEntry) {
+	gated.entered <- struct{}{}
+	<-gated.release
+	gated.mockBackend.Send(event)
+}
+
+// fill block the send loop with first event then fill the queue with levels
+func fill(backend slf4go.Backend, gated *gatedBackend, levels ...slf4go.Level) {
+	backend.Send(&slf4go.EventEntry{Message: "first", Level: slf4go.ERROR})
+	<-gated.entered
+
+	for _, level := range levels {
+		backend.Send(&slf4go.EventEntry{Message: level.String(), Level: level})
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	filter := newCachedFilter("cached")
+	filter.cachedSize = 2
+	filter.reportInterval = 0
+
+	filter.overflow = overflowDropNewest
+	gated := newGatedBackend()
+	backend := filter.MakeChain(gated)
+	fill(backend, gated, slf4go.INFO, slf4go.WARN, slf4go.ERROR)
+
+	require.Equal(t, slf4go.BackendStats{Enqueued: 3, Dropped: 1, Depth: 3}, backend.(slf4go.StatsBackend).Stats())
+
+	close(gated.release)
+	require.NoError(t, slf4go.CloseBackend(context.Background(), backend))
+	require.Equal(t, "warn", gated.events[2].Message)
+
+	filter.overflow = overflowDropOldest
+	gated = newGatedBackend()
+	backend = filter.MakeChain(gated)
+	fill(backend, gated, slf4go.INFO, slf4go.WARN, slf4go.ERROR)
+	close(gated.release)
+	require.NoError(t, slf4go.CloseBackend(context.Background(), backend))
+	require.Equal(t, 3, len(gated.events))
+	require.Equal(t, "warn", gated.events[1].Message)
+
+	filter.overflow = overflowDropLevel
+	filter.dropLevel = slf4go.WARN
+	gated = newGatedBackend()
+	backend = filter.MakeChain(gated)
+	fill(backend, gated, slf4go.INFO, slf4go.ERROR, slf4go.DEBUG, slf4go.ERROR)
+	close(gated.release)
+	require.NoError(t, slf4go.CloseBackend(context.Background(), backend))
+	require.Equal(t, 3, len(gated.events))
+	require.Equal(t, slf4go.ERROR, gated.events[1].Level)
+	require.Equal(t, slf4go.ERROR, gated.events[2].Level)
+
+	filter.overflow = overflowTimeout
+	filter.timeout = 10 * time.Millisecond
+	gated = newGatedBackend()
+	backend = filter.MakeChain(gated)
+	fill(backend, gated, slf4go.INFO, slf4go.WARN, slf4go.ERROR)
+	require.Equal(t, uint64(1), backend.(slf4go.StatsBackend).Stats().Dropped)
+	close(gated.release)
+	require.NoError(t, slf4go.CloseBackend(context.Background(), backend))
+	require.Equal(t, 3, len(gated.events))
+}
+
+func TestDropReport(t *testing.T) {
+	filter := newCachedFilter("cached")
+	filter.cachedSize = 1
+	filter.overflow = overflowDropNewest
+	filter.reportInterval = time.Millisecond
+
+	gated := newGatedBackend()
+	backend := filter.MakeChain(gated)
+	fill(backend, gated, slf4go.INFO, slf4go.INFO, slf4go.INFO)
+	close(gated.release)
+
+	require.NoError(t, slf4go.CloseBackend(context.Background(), backend))
+
+	last := gated.events[len(gated.events)-1]
+
+	require.Equal(t, slf4go.WARN, last.Level)
+	require.Equal(t, "2 events dropped", last.Message)
+}
+
+type batchBackend struct {
+	mockBackend
+	batches [][]*slf4go.EventEntry
+}
+
+func (batch *batchBackend) SendBatch(events []*slf4go.EventEntry) {
+	batch.Lock()
+	batch.batches = append(batch.batches, events)
+	batch.Unlock()
+
+	for _, event := range events {
+		batch.mockBackend.Send(event)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	filter := newCachedFilter("cached")
+	filter.batchSize = 10
+	filter.batchLatency = time.Hour
+
+	batch := &batchBackend{}
+	backend := filter.MakeChain(batch)
+
+	for i := 0; i < 25; i++ {
+		backend.Send(&slf4go.EventEntry{Message: "test"})
+	}
+
+	require.NoError(t, slf4go.FlushBackend(context.Background(), backend))
+	require.Equal(t, 25, batch.count())
+	require.Equal(t, 3, len(batch.batches))
+	require.Equal(t, 10, len(batch.batches[0]))
+	require.Equal(t, 10, len(batch.batches[1]))
+
+	filter.batchSize = 0
+	filter.batchBytes = 2 * entrySize(&slf4go.EventEntry{Message: "test"})
+	filter.batchLatency = 10 * time.Millisecond
+
+	batch = &batchBackend{}
+	backend = filter.MakeChain(batch)
+
+	for i := 0; i < 3; i++ {
+		backend.Send(&slf4go.EventEntry{Message: "test"})
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	require.Equal(t, 3, batch.count())
+	require.Equal(t, 2, len(batch.batches))
+	require.Equal(t, 2, len(batch.batches[0]))
+
+	require.NoError(t, slf4go.CloseBackend(context.Background(), backend))
+}
@@ -0,0 +1,324 @@
+package slf4go
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/scf4go"
+)
+
+// ErrFormatter .
+var ErrFormatter = errors.New("formatter not found", errors.WithVendor(errVendor), errors.WithCode(-4))
+
+// Formatter encode event entry into one record without trailing newline
+type Formatter interface {
+	Format(entry *EventEntry) ([]byte, error)
+}
+
+// FormatterConstructor create formatter with the config of the backend using it
+type FormatterConstructor func(config scf4go.Config) (Formatter, error)
+
+var formattersMutex sync.RWMutex
+var formatters = map[string]FormatterConstructor{
+	"json":   newJSONFormatter,
+	"clef":   newCLEFFormatter,
+	"logfmt": newLogfmtFormatter,
+	"text":   newTextFormatter,
+}
+
+// RegisterFormatter register formatter selectable by backends with config 'format: <name>'
+func RegisterFormatter(name string, constructor FormatterConstructor) {
+	formattersMutex.Lock()
+	defer formattersMutex.Unlock()
+
+	formatters[name] = constructor
+}
+
+// NewFormatter create formatter named by backend config key 'format', def is used if format is not set
+func NewFormatter(config scf4go.Config, def string) (Formatter, error) {
+	name := config.Get("format").String(def)
+
+	formattersMutex.RLock()
+	constructor, ok := formatters[name]
+	formattersMutex.RUnlock()
+
+	if !ok {
+		return nil, errors.Wrap(ErrFormatter, "formatter %s not found", name)
+	}
+
+	return constructor(config)
+}
+
+// attrName strip the placeholder '@' prefix of attribute key
+func attrName(key string) string {
+	return strings.TrimPrefix(key, "@")
+}
+
+func sortedAttrs(attrs map[string]interface{}) []string {
+	keys := make([]string, 0, len(attrs))
+
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// FormatFunc adapter of ordinary function as Formatter
+type FormatFunc func(entry *EventEntry) ([]byte, error)
+
+// Format .
+func (f FormatFunc) Format(entry *EventEntry) ([]byte, error) {
+	return f(entry)
+}
+
+// JSONFormatter encode entry with EventEntry JSON keys
+var JSONFormatter Formatter = FormatFunc(func(entry *EventEntry) ([]byte, error) {
+	return json.Marshal(entry)
+})
+
+func newJSONFormatter(config scf4go.Config) (Formatter, error) {
+	return JSONFormatter, nil
+}
+
+// clefLevels level names of Compact Log Event Format, INFO is omitted as the default level
+var clefLevels = map[Level]string{
+	TRACE: "Verbose",
+	DEBUG: "Debug",
+	WARN:  "Warning",
+	ERROR: "Error",
+}
+
+func writeJSONField(buff *bytes.Buffer, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		return errors.Wrap(err, "marshal %s error", key)
+	}
+
+	if buff.Len() > 1 {
+		buff.WriteByte(',')
+	}
+
+	name, _ := json.Marshal(key)
+
+	buff.Write(name)
+	buff.WriteByte(':')
+	buff.Write(data)
+
+	return nil
+}
+
+// formatCLEF encode entry with Compact Log Event Format, attributes become top-level properties
+func formatCLEF(entry *EventEntry) ([]byte, error) {
+	var buff bytes.Buffer
+
+	buff.WriteByte('{')
+
+	fields := []struct {
+		key   string
+		value interface{}
+		skip  bool
+	}{
+		{"@t", entry.Timestamp.Format(time.RFC3339Nano), false},
+		{"@m", entry.Message, false},
+		{"@mt", entry.Template, entry.Template == ""},
+		{"@i", entry.EventType, entry.EventType == ""},
+		{"@l", clefLevels[entry.Level], entry.Level == INFO},
+	}
+
+	for _, field := range fields {
+		if field.skip {
+			continue
+		}
+
+		if err := writeJSONField(&buff, field.key, field.value); err != nil {
+			return nil, err
+		}
+	}
+
+	keys := sortedAttrs(entry.Attrs)
+
+	for _, key := range keys {
+		if exception, ok := entry.Attrs[key].(error); ok {
+			if err := writeJSONField(&buff, "@x", exception.Error()); err != nil {
+				return nil, err
+			}
+
+			break
+		}
+	}
+
+	for _, key := range keys {
+		name := attrName(key)
+
+		if strings.HasPrefix(name, "@") {
+			// CLEF escapes property names starting with '@'
+			name = "@" + name
+		}
+
+		value := entry.Attrs[key]
+
+		if exception, ok := value.(error); ok {
+			value = exception.Error()
+		}
+
+		if err := writeJSONField(&buff, name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if entry.Source != "" {
+		if err := writeJSONField(&buff, "SourceContext", entry.Source); err != nil {
+			return nil, err
+		}
+	}
+
+	if entry.File != "" {
+		if err := writeJSONField(&buff, "Caller", fmt.Sprintf("%s:%d", entry.File, entry.Line)); err != nil {
+			return nil, err
+		}
+	}
+
+	buff.WriteByte('}')
+
+	return buff.Bytes(), nil
+}
+
+// CLEFFormatter encode entry with Compact Log Event Format, the default format of file backend
+var CLEFFormatter Formatter = FormatFunc(formatCLEF)
+
+func newCLEFFormatter(config scf4go.Config) (Formatter, error) {
+	return CLEFFormatter, nil
+}
+
+func logfmtValue(value string) string {
+	if value == "" {
+		return `""`
+	}
+
+	for _, r := range value {
+		if r == '=' || r == '"' || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return strconv.Quote(value)
+		}
+	}
+
+	return value
+}
+
+// AttrString render attribute value, strings are kept as is and other values are rendered as placeholders
+func AttrString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	data, err := formatArg(value)
+
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return data
+}
+
+type logfmtFormatter struct {
+	timestamp string
+}
+
+func newLogfmtFormatter(config scf4go.Config) (Formatter, error) {
+	return &logfmtFormatter{
+		timestamp: config.Get("timestamp").String(time.RFC3339Nano),
+	}, nil
+}
+
+func (formatter *logfmtFormatter) Format(entry *EventEntry) ([]byte, error) {
+	var buff bytes.Buffer
+
+	write := func(key string, value string) {
+		if buff.Len() > 0 {
+			buff.WriteByte(' ')
+		}
+
+		buff.WriteString(key)
+		buff.WriteByte('=')
+		buff.WriteString(logfmtValue(value))
+	}
+
+	write("time", entry.Timestamp.Format(formatter.timestamp))
+	write("level", entry.Level.String())
+	write("logger", entry.Source)
+	write("msg", entry.Message)
+
+	for _, key := range sortedAttrs(entry.Attrs) {
+		write(attrName(key), AttrString(entry.Attrs[key]))
+	}
+
+	if entry.File != "" {
+		write("caller", fmt.Sprintf("%s:%d", entry.File, entry.Line))
+	}
+
+	return buff.Bytes(), nil
+}
+
+var defaultTextTemplate = `{{time .Timestamp}} {{upper .Level.String}} {{.Source}} {{.Message}}`
+
+var textFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"attr": func(entry *EventEntry, name string) string {
+		value, ok := entry.Attrs["@"+attrName(name)]
+
+		if !ok {
+			return ""
+		}
+
+		return AttrString(value)
+	},
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
+
+type textFormatter struct {
+	template *template.Template
+}
+
+func newTextFormatter(config scf4go.Config) (Formatter, error) {
+	layout := config.Get("timestamp").String(time.RFC3339)
+
+	funcs := template.FuncMap{
+		"time": func(timestamp time.Time) string {
+			return timestamp.Format(layout)
+		},
+	}
+
+	tpl, err := template.New("text").Funcs(textFuncs).Funcs(funcs).Parse(config.Get("template").String(defaultTextTemplate))
+
+	if err != nil {
+		return nil, errors.Wrap(err, "parse text formatter template error")
+	}
+
+	return &textFormatter{template: tpl}, nil
+}
+
+func (formatter *textFormatter) Format(entry *EventEntry) ([]byte, error) {
+	var buff bytes.Buffer
+
+	if err := formatter.template.Execute(&buff, entry); err != nil {
+		return nil, errors.Wrap(err, "execute text formatter template error")
+	}
+
+	return bytes.TrimSuffix(buff.Bytes(), []byte("\n")), nil
+}
@@ -1,43 +1,65 @@
 package slf4go
 
 import (
+	"context"
 	"sync"
 
 	"github.com/libs4go/scf4go"
 )
 
 var once sync.Once
-var factory *loggerFactory
+var defaultFactory *Factory
 
-func getLoggerFactor() *loggerFactory {
+// Default get the default factory used by package-level functions
+func Default() *Factory {
 	once.Do(func() {
-		factory = newLoggerFactory()
+		defaultFactory = New()
 	})
 
-	return factory
+	return defaultFactory
 }
 
 // RegisterBackend .
 func RegisterBackend(name string, backend Backend) {
-	getLoggerFactor().registerBackend(name, backend)
+	Default().RegisterBackend(name, backend)
 }
 
 // RegisterFilter create filter chain with call order
 func RegisterFilter(filter Filter) {
-	getLoggerFactor().registerFilter(filter)
+	Default().RegisterFilter(filter)
 }
 
 // Sync sync flush all logger event
 func Sync() {
-	getLoggerFactor().sync()
+	Default().Sync()
+}
+
+// Flush drain all backends pending events and keep them working
+func Flush(ctx context.Context) error {
+	return Default().Flush(ctx)
+}
+
+// Stats get statistics of backends implementing StatsBackend indexed by backend name
+func Stats() map[string]BackendStats {
+	return Default().Stats()
+}
+
+// CollectMetrics get a snapshot of logging pipeline metrics
+func CollectMetrics() *Metrics {
+	return Default().CollectMetrics()
+}
+
+// Shutdown drain and close all filters and backends, loggers are disabled after shutdown
+func Shutdown(ctx context.Context) error {
+	return Default().Shutdown(ctx)
 }
 
 // Get create or get logger with name
 func Get(name string) Logger {
-	return getLoggerFactor().createLogger(name)
+	return Default().Get(name)
 }
 
 // Config config loggers with scf4go
 func Config(config scf4go.Config) error {
-	return getLoggerFactor().setConfig(config)
+	return Default().Config(config)
 }
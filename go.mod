@@ -1,13 +1,23 @@
 module github.com/libs4go/slf4go
 
-go 1.12
+go 1.17
 
 require (
 	github.com/fatih/color v1.7.0
 	github.com/ghodss/yaml v1.0.0
 	github.com/libs4go/errors v0.0.3
 	github.com/libs4go/scf4go v0.0.1
-	github.com/mattn/go-colorable v0.1.4 // indirect
-	github.com/mattn/go-isatty v0.0.10 // indirect
+	github.com/mattn/go-colorable v0.1.4
+	github.com/mattn/go-isatty v0.0.10
 	github.com/stretchr/testify v1.4.0
 )
+
+require (
+	github.com/bitly/go-simplejson v0.5.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/imdario/mergo v0.3.8 // indirect
+	github.com/libs4go/sdi4go v0.0.0-20191107032536-9900892950bc // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.0.0-20191008105621-543471e840be // indirect
+	gopkg.in/yaml.v2 v2.2.2 // indirect
+)
@@ -0,0 +1,347 @@
+// Package queue byte bounded queue of formatted entries shared by remote backends,
+// entries are taken in batches by one send loop
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/slf4go"
+)
+
+// overflow policies applied when the queue is full
+const (
+	DropNewest = "drop_newest" // drop the new entry
+	DropOldest = "drop_oldest" // drop the oldest queued entries
+)
+
+// Options queue limits, zero batch limits mean unlimited and zero BatchLatency sends without waiting
+type Options struct {
+	MaxBytes     int64 // max bytes of queued entries
+	Overflow     string
+	BatchSize    int
+	BatchBytes   int64
+	BatchLatency time.Duration
+}
+
+// Queue FIFO of formatted entries
+type Queue struct {
+	mutex     sync.Mutex
+	options   Options
+	items     [][]byte
+	bytes     int64         // bytes of queued entries
+	queuedAt  time.Time     // time when the queue became non-empty
+	changed   chan struct{} // closed and recreated when queue state changed
+	waiters   int
+	flushing  int    // flush callers waiting, batches are taken without waiting latency
+	pending   int    // entries taken by send loop but not done yet
+	enqueued  uint64 // entries accepted
+	processed uint64 // entries done or dropped after accepted
+	dropped   uint64
+	closed    bool
+}
+
+// New create queue with options
+func New(options Options) *Queue {
+	return &Queue{
+		options: options,
+		changed: make(chan struct{}),
+	}
+}
+
+// SetOptions change queue limits, queued entries are kept
+func (queue *Queue) SetOptions(options Options) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	queue.options = options
+	queue.notify()
+}
+
+// wait release lock until queue state changed or ctx done, must be called with lock held
+func (queue *Queue) wait(ctx context.Context) error {
+	changed := queue.changed
+	queue.waiters++
+	queue.mutex.Unlock()
+
+	var err error
+
+	select {
+	case <-changed:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	queue.mutex.Lock()
+	queue.waiters--
+
+	return err
+}
+
+// notify wakeup all waiters, must be called with lock held
+func (queue *Queue) notify() {
+	if queue.waiters == 0 {
+		return
+	}
+
+	close(queue.changed)
+	queue.changed = make(chan struct{})
+}
+
+// dropOldest remove count entries from the queue head, must be called with lock held
+func (queue *Queue) dropOldest(count int) {
+	for i := 0; i < count; i++ {
+		queue.bytes -= int64(len(queue.items[i]))
+		queue.items[i] = nil
+	}
+
+	queue.items = queue.items[count:]
+	queue.processed += uint64(count)
+	queue.dropped += uint64(count)
+}
+
+// makeRoom apply overflow policy until size bytes fit into the queue, return false
+// if the new entry should be dropped, must be called with lock held
+func (queue *Queue) makeRoom(size int64) bool {
+	if size > queue.options.MaxBytes {
+		return false
+	}
+
+	excess := queue.bytes + size - queue.options.MaxBytes
+
+	if excess <= 0 {
+		return true
+	}
+
+	if queue.options.Overflow == DropNewest {
+		return false
+	}
+
+	count := 0
+
+	for ; excess > 0; count++ {
+		excess -= int64(len(queue.items[count]))
+	}
+
+	queue.dropOldest(count)
+
+	return true
+}
+
+// Push append entry, return false if the entry is dropped by overflow policy or closed queue
+func (queue *Queue) Push(item []byte) bool {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if queue.closed || !queue.makeRoom(int64(len(item))) {
+		queue.dropped++
+		queue.notify()
+		return false
+	}
+
+	if len(queue.items) == 0 {
+		queue.queuedAt = time.Now()
+	}
+
+	queue.items = append(queue.items, item)
+	queue.bytes += int64(len(item))
+	queue.enqueued++
+	queue.notify()
+
+	return true
+}
+
+// batchReady check if queued entries should be taken now, must be called with lock held
+func (queue *Queue) batchReady() bool {
+	if len(queue.items) == 0 {
+		return false
+	}
+
+	options := queue.options
+
+	if queue.closed || queue.flushing > 0 || options.BatchLatency <= 0 {
+		return true
+	}
+
+	if options.BatchSize > 0 && len(queue.items) >= options.BatchSize {
+		return true
+	}
+
+	if options.BatchBytes > 0 && queue.bytes >= options.BatchBytes {
+		return true
+	}
+
+	return !time.Now().Before(queue.queuedAt.Add(options.BatchLatency))
+}
+
+// Take wait until a batch limited by batch size and bytes is ready and remove it from the queue head,
+// nil is returned if the queue is closed and empty. Call Done, Drop or Requeue after the batch is handled
+func (queue *Queue) Take() [][]byte {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	for !queue.closed && !queue.batchReady() {
+		if len(queue.items) == 0 {
+			queue.wait(context.Background())
+			continue
+		}
+
+		ctx, cancel := context.WithDeadline(context.Background(), queue.queuedAt.Add(queue.options.BatchLatency))
+		queue.wait(ctx)
+		cancel()
+	}
+
+	count := 0
+
+	var size int64
+
+	for count < len(queue.items) {
+		if queue.options.BatchSize > 0 && count >= queue.options.BatchSize {
+			break
+		}
+
+		length := int64(len(queue.items[count]))
+
+		if queue.options.BatchBytes > 0 && count > 0 && size+length > queue.options.BatchBytes {
+			break
+		}
+
+		size += length
+		count++
+	}
+
+	if count == 0 {
+		return nil
+	}
+
+	batch := make([][]byte, count)
+	copy(batch, queue.items)
+
+	for i := range batch {
+		queue.items[i] = nil
+	}
+
+	if count == len(queue.items) {
+		queue.items = nil
+	} else {
+		queue.items = queue.items[count:]
+	}
+
+	queue.bytes -= size
+	queue.pending = count
+
+	return batch
+}
+
+// Done mark the taken batch as handled, sent or failed
+func (queue *Queue) Done() {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	queue.processed += uint64(queue.pending)
+	queue.pending = 0
+	queue.notify()
+}
+
+// Drop mark the taken batch as dropped
+func (queue *Queue) Drop() {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	queue.processed += uint64(queue.pending)
+	queue.dropped += uint64(queue.pending)
+	queue.pending = 0
+	queue.notify()
+}
+
+// Requeue put the taken batch back to the queue head, the oldest entries are dropped
+// if they don't fit into the queue any more
+func (queue *Queue) Requeue(batch [][]byte) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	var size int64
+
+	for _, item := range batch {
+		size += int64(len(item))
+	}
+
+	for len(batch) > 0 && queue.bytes+size > queue.options.MaxBytes {
+		size -= int64(len(batch[0]))
+		batch = batch[1:]
+		queue.processed++
+		queue.dropped++
+	}
+
+	if len(queue.items) == 0 {
+		queue.queuedAt = time.Now()
+	}
+
+	queue.items = append(batch[:len(batch):len(batch)], queue.items...)
+	queue.bytes += size
+	queue.pending = 0
+	queue.notify()
+}
+
+// Abort drop all queued entries
+func (queue *Queue) Abort() {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	queue.dropOldest(len(queue.items))
+	queue.notify()
+}
+
+// Close stop accepting entries, queued entries are still taken without waiting latency
+func (queue *Queue) Close() {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	queue.closed = true
+	queue.notify()
+}
+
+// Flush wait until all entries accepted before calling Flush are done or dropped
+func (queue *Queue) Flush(ctx context.Context) error {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	target := queue.enqueued
+
+	queue.flushing++
+	queue.notify()
+
+	defer func() {
+		queue.flushing--
+	}()
+
+	for queue.processed < target {
+		if err := queue.wait(ctx); err != nil {
+			return errors.Wrap(err, "flush queued events(%d) error", target-queue.processed)
+		}
+	}
+
+	return nil
+}
+
+// Bytes get bytes of queued entries
+func (queue *Queue) Bytes() int64 {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	return queue.bytes
+}
+
+// Stats get enqueued, dropped and depth statistics
+func (queue *Queue) Stats() slf4go.BackendStats {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	return slf4go.BackendStats{
+		Enqueued: queue.enqueued,
+		Dropped:  queue.dropped,
+		Depth:    len(queue.items) + queue.pending,
+	}
+}
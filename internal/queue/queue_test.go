@@ -0,0 +1,90 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverflow(t *testing.T) {
+	for _, overflow := range []string{DropOldest, DropNewest} {
+		queue := New(Options{MaxBytes: 90, Overflow: overflow})
+
+		for i := 0; i < 50; i++ {
+			queue.Push([]byte(fmt.Sprintf("entry %02d\n", i)))
+		}
+
+		require.Equal(t, int64(90), queue.Bytes())
+
+		stats := queue.Stats()
+		require.Equal(t, 10, stats.Depth)
+		require.Equal(t, uint64(40), stats.Dropped)
+
+		queue.Close()
+
+		batch := queue.Take()
+		require.Len(t, batch, 10)
+
+		if overflow == DropOldest {
+			require.Equal(t, "entry 49\n", string(batch[9]))
+		} else {
+			require.Equal(t, "entry 09\n", string(batch[9]))
+		}
+
+		queue.Done()
+
+		require.Nil(t, queue.Take())
+		require.False(t, queue.Push([]byte("closed")))
+	}
+}
+
+func TestBatch(t *testing.T) {
+	queue := New(Options{MaxBytes: 1024, BatchSize: 3, BatchBytes: 20, BatchLatency: time.Hour})
+
+	for i := 0; i < 4; i++ {
+		queue.Push([]byte(fmt.Sprintf("entry %d\n", i)))
+	}
+
+	// limited by batch_bytes
+	batch := queue.Take()
+	require.Len(t, batch, 2)
+
+	// failed batch is sent again first
+	queue.Requeue(batch)
+	require.Equal(t, 4, queue.Stats().Depth)
+
+	batch = queue.Take()
+	require.Equal(t, "entry 0\n", string(batch[0]))
+	queue.Done()
+
+	// remaining entries wait for latency unless flushed
+	done := make(chan error)
+
+	go func() {
+		done <- queue.Flush(context.Background())
+	}()
+
+	batch = queue.Take()
+	require.Len(t, batch, 2)
+	queue.Drop()
+
+	require.NoError(t, <-done)
+
+	stats := queue.Stats()
+	require.Equal(t, uint64(4), stats.Enqueued)
+	require.Equal(t, uint64(2), stats.Dropped)
+	require.Zero(t, stats.Depth)
+
+	queue.Push([]byte("pending"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	require.Error(t, queue.Flush(ctx))
+
+	queue.Abort()
+	require.NoError(t, queue.Flush(context.Background()))
+}
@@ -0,0 +1,25 @@
+package slf4go
+
+import "context"
+
+// FlushBackend drain backend pending events, fallback to Backend.Sync
+// if the backend does not implement Flusher
+func FlushBackend(ctx context.Context, backend Backend) error {
+	if flusher, ok := backend.(Flusher); ok {
+		return flusher.Flush(ctx)
+	}
+
+	backend.Sync()
+
+	return ctx.Err()
+}
+
+// CloseBackend drain backend pending events and release its resources,
+// fallback to FlushBackend if the backend does not implement Closer
+func CloseBackend(ctx context.Context, backend Backend) error {
+	if closer, ok := backend.(Closer); ok {
+		return closer.Close(ctx)
+	}
+
+	return FlushBackend(ctx, backend)
+}
@@ -0,0 +1,181 @@
+package slf4go
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LatencyBuckets upper bounds of send latency histogram buckets
+var LatencyBuckets = []time.Duration{
+	time.Microsecond * 10,
+	time.Microsecond * 100,
+	time.Millisecond,
+	time.Millisecond * 10,
+	time.Millisecond * 100,
+	time.Second,
+}
+
+// EventMetrics events count of one level/logger/backend combination
+type EventMetrics struct {
+	Level   Level  `json:"level"`
+	Logger  string `json:"logger"`
+	Backend string `json:"backend"`
+	Count   uint64 `json:"count"`
+}
+
+// LatencyMetrics Backend.Send latency histogram observed by loggers
+type LatencyMetrics struct {
+	Count   uint64        `json:"count"`
+	Sum     time.Duration `json:"sum"`
+	Buckets []uint64      `json:"buckets"` // cumulative counts of LatencyBuckets
+}
+
+// BackendMetrics backend statistics and send latency
+type BackendMetrics struct {
+	BackendStats
+	Latency LatencyMetrics `json:"latency"`
+}
+
+// Metrics logging pipeline metrics snapshot
+type Metrics struct {
+	Events   []EventMetrics            `json:"events"`
+	Backends map[string]BackendMetrics `json:"backends"`
+}
+
+type eventKey struct {
+	level   Level
+	logger  string
+	backend string
+}
+
+type latencyHistogram struct {
+	count   uint64
+	sum     int64
+	buckets []uint64
+}
+
+func (histogram *latencyHistogram) observe(latency time.Duration) {
+	atomic.AddUint64(&histogram.count, 1)
+	atomic.AddInt64(&histogram.sum, int64(latency))
+
+	for i, bound := range LatencyBuckets {
+		if latency <= bound {
+			atomic.AddUint64(&histogram.buckets[i], 1)
+			return
+		}
+	}
+}
+
+func (histogram *latencyHistogram) snapshot() LatencyMetrics {
+	metrics := LatencyMetrics{
+		Count:   atomic.LoadUint64(&histogram.count),
+		Sum:     time.Duration(atomic.LoadInt64(&histogram.sum)),
+		Buckets: make([]uint64, len(histogram.buckets)),
+	}
+
+	var cumulative uint64
+
+	for i := range histogram.buckets {
+		cumulative += atomic.LoadUint64(&histogram.buckets[i])
+		metrics.Buckets[i] = cumulative
+	}
+
+	return metrics
+}
+
+type metricsRegistry struct {
+	sync.RWMutex
+	events  map[eventKey]*uint64
+	latency map[string]*latencyHistogram
+}
+
+func newMetricsRegistry() *metricsRegistry {
+	return &metricsRegistry{
+		events:  make(map[eventKey]*uint64),
+		latency: make(map[string]*latencyHistogram),
+	}
+}
+
+func (registry *metricsRegistry) record(level Level, logger string, backend string, latency time.Duration) {
+	key := eventKey{level: level, logger: logger, backend: backend}
+
+	registry.RLock()
+	counter, ok := registry.events[key]
+	histogram, found := registry.latency[backend]
+	registry.RUnlock()
+
+	if !ok || !found {
+		registry.Lock()
+
+		if counter, ok = registry.events[key]; !ok {
+			counter = new(uint64)
+			registry.events[key] = counter
+		}
+
+		if histogram, found = registry.latency[backend]; !found {
+			histogram = &latencyHistogram{buckets: make([]uint64, len(LatencyBuckets))}
+			registry.latency[backend] = histogram
+		}
+
+		registry.Unlock()
+	}
+
+	atomic.AddUint64(counter, 1)
+	histogram.observe(latency)
+}
+
+// CollectMetrics get a snapshot of logging pipeline metrics
+func (factory *Factory) CollectMetrics() *Metrics {
+	registry := factory.metrics
+
+	metrics := &Metrics{
+		Backends: make(map[string]BackendMetrics),
+	}
+
+	registry.RLock()
+
+	for key, counter := range registry.events {
+		metrics.Events = append(metrics.Events, EventMetrics{
+			Level:   key.level,
+			Logger:  key.logger,
+			Backend: key.backend,
+			Count:   atomic.LoadUint64(counter),
+		})
+	}
+
+	latency := make(map[string]LatencyMetrics, len(registry.latency))
+
+	for name, histogram := range registry.latency {
+		latency[name] = histogram.snapshot()
+	}
+
+	registry.RUnlock()
+
+	sort.Slice(metrics.Events, func(i, j int) bool {
+		a, b := metrics.Events[i], metrics.Events[j]
+
+		if a.Backend != b.Backend {
+			return a.Backend < b.Backend
+		}
+
+		if a.Logger != b.Logger {
+			return a.Logger < b.Logger
+		}
+
+		return a.Level < b.Level
+	})
+
+	for name, stats := range factory.Stats() {
+		metrics.Backends[name] = BackendMetrics{BackendStats: stats}
+	}
+
+	for name, histogram := range latency {
+		backend := metrics.Backends[name]
+		backend.Latency = histogram
+		metrics.Backends[name] = backend
+	}
+
+	return metrics
+}
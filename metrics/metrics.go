@@ -0,0 +1,145 @@
+// Package metrics expose slf4go pipeline metrics via expvar and Prometheus text exposition format
+package metrics
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/libs4go/slf4go"
+)
+
+// Source metrics snapshot provider
+type Source func() *slf4go.Metrics
+
+type handler struct {
+	source Source
+}
+
+// Handler create http.Handler serving slf4go metrics in Prometheus text format
+func Handler() http.Handler {
+	return HandlerFor(slf4go.CollectMetrics)
+}
+
+// HandlerFor create http.Handler serving metrics from source in Prometheus text format
+func HandlerFor(source Source) http.Handler {
+	return &handler{source: source}
+}
+
+func (handler *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	if err := WriteText(w, handler.source()); err != nil {
+		println(fmt.Sprintf("write metrics error: %s", err))
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func labels(pairs ...string) string {
+	var builder strings.Builder
+
+	builder.WriteString("{")
+
+	for i := 0; i < len(pairs); i += 2 {
+		if i > 0 {
+			builder.WriteString(",")
+		}
+
+		builder.WriteString(pairs[i])
+		builder.WriteString(`="`)
+		builder.WriteString(labelEscaper.Replace(pairs[i+1]))
+		builder.WriteString(`"`)
+	}
+
+	builder.WriteString("}")
+
+	return builder.String()
+}
+
+func header(w io.Writer, name string, kind string, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// WriteText write metrics snapshot in Prometheus text exposition format
+func WriteText(writer io.Writer, metrics *slf4go.Metrics) error {
+	w := bufio.NewWriter(writer)
+
+	header(w, "slf4go_events_total", "counter", "Events sent to backends by level, logger and backend.")
+
+	for _, event := range metrics.Events {
+		fmt.Fprintf(w, "slf4go_events_total%s %d\n",
+			labels("level", event.Level.String(), "logger", event.Logger, "backend", event.Backend), event.Count)
+	}
+
+	var names []string
+
+	for name := range metrics.Backends {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	counters := []struct {
+		name  string
+		kind  string
+		help  string
+		value func(stats slf4go.BackendMetrics) string
+	}{
+		{"slf4go_backend_written_bytes_total", "counter", "Bytes written by backends.", func(stats slf4go.BackendMetrics) string {
+			return strconv.FormatUint(stats.Written, 10)
+		}},
+		{"slf4go_backend_failed_total", "counter", "Events backends failed to write.", func(stats slf4go.BackendMetrics) string {
+			return strconv.FormatUint(stats.Failed, 10)
+		}},
+		{"slf4go_backend_enqueued_total", "counter", "Events accepted by asynchronous queues.", func(stats slf4go.BackendMetrics) string {
+			return strconv.FormatUint(stats.Enqueued, 10)
+		}},
+		{"slf4go_backend_dropped_total", "counter", "Events dropped by asynchronous queues.", func(stats slf4go.BackendMetrics) string {
+			return strconv.FormatUint(stats.Dropped, 10)
+		}},
+		{"slf4go_backend_queue_depth", "gauge", "Events waiting for delivery.", func(stats slf4go.BackendMetrics) string {
+			return strconv.Itoa(stats.Depth)
+		}},
+	}
+
+	for _, counter := range counters {
+		header(w, counter.name, counter.kind, counter.help)
+
+		for _, name := range names {
+			fmt.Fprintf(w, "%s%s %s\n", counter.name, labels("backend", name), counter.value(metrics.Backends[name]))
+		}
+	}
+
+	header(w, "slf4go_send_latency_seconds", "histogram", "Backend send latency observed by loggers.")
+
+	for _, name := range names {
+		latency := metrics.Backends[name].Latency
+
+		if len(latency.Buckets) == 0 {
+			continue
+		}
+
+		for i, bound := range slf4go.LatencyBuckets {
+			fmt.Fprintf(w, "slf4go_send_latency_seconds_bucket%s %d\n",
+				labels("backend", name, "le", strconv.FormatFloat(bound.Seconds(), 'g', -1, 64)), latency.Buckets[i])
+		}
+
+		fmt.Fprintf(w, "slf4go_send_latency_seconds_bucket%s %d\n", labels("backend", name, "le", "+Inf"), latency.Count)
+		fmt.Fprintf(w, "slf4go_send_latency_seconds_sum%s %s\n", labels("backend", name), strconv.FormatFloat(latency.Sum.Seconds(), 'g', -1, 64))
+		fmt.Fprintf(w, "slf4go_send_latency_seconds_count%s %d\n", labels("backend", name), latency.Count)
+	}
+
+	return w.Flush()
+}
+
+func init() {
+	expvar.Publish("slf4go", expvar.Func(func() interface{} {
+		return slf4go.CollectMetrics()
+	}))
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"expvar"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/libs4go/slf4go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	snapshot := &slf4go.Metrics{
+		Events: []slf4go.EventMetrics{
+			{Level: slf4go.INFO, Logger: `a"b`, Backend: "file", Count: 3},
+		},
+		Backends: map[string]slf4go.BackendMetrics{
+			"file": {
+				BackendStats: slf4go.BackendStats{Written: 100, Dropped: 2, Depth: 1},
+				Latency: slf4go.LatencyMetrics{
+					Count:   3,
+					Sum:     time.Millisecond * 3,
+					Buckets: []uint64{0, 1, 3, 3, 3, 3},
+				},
+			},
+		},
+	}
+
+	server := httptest.NewServer(HandlerFor(func() *slf4go.Metrics { return snapshot }))
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	text := string(body)
+
+	require.Contains(t, text, `slf4go_events_total{level="info",logger="a\"b",backend="file"} 3`)
+	require.Contains(t, text, `slf4go_backend_written_bytes_total{backend="file"} 100`)
+	require.Contains(t, text, `slf4go_backend_dropped_total{backend="file"} 2`)
+	require.Contains(t, text, `slf4go_backend_queue_depth{backend="file"} 1`)
+	require.Contains(t, text, `slf4go_send_latency_seconds_bucket{backend="file",le="0.001"} 3`)
+	require.Contains(t, text, `slf4go_send_latency_seconds_bucket{backend="file",le="+Inf"} 3`)
+	require.Contains(t, text, `slf4go_send_latency_seconds_sum{backend="file"} 0.003`)
+
+	require.NotNil(t, expvar.Get("slf4go"))
+}
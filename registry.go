@@ -0,0 +1,282 @@
+package slf4go
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/scf4go"
+)
+
+// BackendConstructor create backend instance named name
+type BackendConstructor func(name string) Backend
+
+// FilterConstructor create filter instance named name, the returned filter's Name() should be name
+type FilterConstructor func(name string) Filter
+
+var typesMutex sync.RWMutex
+var backendTypes = make(map[string]BackendConstructor)
+var filterTypes = make(map[string]FilterConstructor)
+
+// RegisterBackendType register backend type shared by all factories,
+// config 'backend: <name>: { type: <typeName> }' creates an instance,
+// type defaults to the instance name
+func RegisterBackendType(typeName string, constructor BackendConstructor) {
+	typesMutex.Lock()
+	defer typesMutex.Unlock()
+
+	backendTypes[typeName] = constructor
+}
+
+// RegisterFilterType register filter type shared by all factories,
+// config 'filter: <name>: { type: <typeName> }' creates an instance,
+// type defaults to the instance name
+func RegisterFilterType(typeName string, constructor FilterConstructor) {
+	typesMutex.Lock()
+	defer typesMutex.Unlock()
+
+	filterTypes[typeName] = constructor
+}
+
+func newBackend(typeName string, name string) (Backend, bool) {
+	typesMutex.RLock()
+	constructor, ok := backendTypes[typeName]
+	typesMutex.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	return constructor(name), true
+}
+
+func hasBackendType(typeName string) bool {
+	typesMutex.RLock()
+	defer typesMutex.RUnlock()
+
+	_, ok := backendTypes[typeName]
+
+	return ok
+}
+
+func newFilter(typeName string, name string) (Filter, bool) {
+	typesMutex.RLock()
+	constructor, ok := filterTypes[typeName]
+	typesMutex.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	return constructor(name), true
+}
+
+// backendInstance backend registered or created by type
+type backendInstance struct {
+	backend Backend
+	filters []string   // filter names wrapping the backend, nil for factory filters
+	base    *chainBase // innermost backend of the filter chain, nil if the backend is not wrapped
+}
+
+// chainBase forward filter chain events to the raw backend, once detached closing the chain
+// only flushes the raw backend, so a replaced chain is closed without closing the backend
+// shared with the new chain
+type chainBase struct {
+	backend  Backend
+	detached int32 // accessed atomically
+}
+
+func (base *chainBase) Send(entry *EventEntry) {
+	base.backend.Send(entry)
+}
+
+func (base *chainBase) SendBatch(entries []*EventEntry) {
+	SendBatch(base.backend, entries)
+}
+
+func (base *chainBase) Sync() {
+	base.backend.Sync()
+}
+
+func (base *chainBase) Config(config scf4go.Config) error {
+	return base.backend.Config(config)
+}
+
+func (base *chainBase) Flush(ctx context.Context) error {
+	return FlushBackend(ctx, base.backend)
+}
+
+func (base *chainBase) Stats() BackendStats {
+	if reporter, ok := base.backend.(StatsBackend); ok {
+		return reporter.Stats()
+	}
+
+	return BackendStats{}
+}
+
+func (base *chainBase) Close(ctx context.Context) error {
+	if atomic.LoadInt32(&base.detached) != 0 {
+		return FlushBackend(ctx, base.backend)
+	}
+
+	return CloseBackend(ctx, base.backend)
+}
+
+func sameFilters(a []string, b []string) bool {
+	if (a == nil) != (b == nil) || len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sectionNames(config scf4go.Config, section string) ([]string, error) {
+	var sections map[string]interface{}
+
+	if err := config.Get(section).Scan(&sections); err != nil {
+		return nil, errors.Wrap(err, "parse %s error", section)
+	}
+
+	var names []string
+
+	for name := range sections {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// makeChain wrap backend instance with filters, must be called with lock held
+func (factory *Factory) makeChain(instance *backendInstance) Backend {
+	base := &chainBase{backend: instance.backend}
+
+	var backend Backend = base
+
+	if instance.filters == nil {
+		for _, filter := range factory.filter {
+			backend = filter.MakeChain(backend)
+		}
+	} else {
+		for _, name := range instance.filters {
+			filter, ok := factory.filters[name]
+
+			if !ok {
+				println(fmt.Sprintf("filter '%s' not found", name))
+				continue
+			}
+
+			backend = filter.MakeChain(backend)
+		}
+	}
+
+	if backend == Backend(base) {
+		instance.base = nil
+		return instance.backend
+	}
+
+	instance.base = base
+
+	return backend
+}
+
+// declareBackend create backend instance by type if not exists and rebuild filter chain if filters changed,
+// must be called with lock held
+func (factory *Factory) declareBackend(name string, typeName string, filters []string) error {
+	instance, ok := factory.raw[name]
+
+	if !ok {
+		backend, found := newBackend(typeName, name)
+
+		if !found {
+			return errors.Wrap(ErrType, "backend '%s' type '%s' not found", name, typeName)
+		}
+
+		instance = &backendInstance{
+			backend: backend,
+			filters: filters,
+		}
+
+		factory.raw[name] = instance
+		factory.backend[name] = factory.makeChain(instance)
+
+		return nil
+	}
+
+	if sameFilters(instance.filters, filters) {
+		return nil
+	}
+
+	if instance.base != nil {
+		// stop filter wrappers of the replaced chain, the raw backend is kept for the new chain
+		atomic.StoreInt32(&instance.base.detached, 1)
+
+		if err := CloseBackend(context.Background(), factory.backend[name]); err != nil {
+			println(fmt.Sprintf("close backend '%s' filter chain before rebuild error: %s", name, err))
+		}
+	} else if err := FlushBackend(context.Background(), factory.backend[name]); err != nil {
+		println(fmt.Sprintf("flush backend '%s' before rebuild filter chain error: %s", name, err))
+	}
+
+	instance.filters = filters
+	factory.backend[name] = factory.makeChain(instance)
+
+	return nil
+}
+
+// declareFilter create filter instance by type if not exists, must be called with lock held
+func (factory *Factory) declareFilter(name string, typeName string) error {
+	if _, ok := factory.filters[name]; ok {
+		return nil
+	}
+
+	filter, found := newFilter(typeName, name)
+
+	if !found {
+		return errors.Wrap(ErrType, "filter '%s' type '%s' not found", name, typeName)
+	}
+
+	factory.filters[name] = filter
+
+	return nil
+}
+
+// lazyBackend create backend instance with the type named name, used when a logger
+// refers an undeclared backend
+func (factory *Factory) lazyBackend(name string) (Backend, bool) {
+	factory.mutex.RLock()
+	backend, ok := factory.backend[name]
+	factory.mutex.RUnlock()
+
+	if ok {
+		return backend, true
+	}
+
+	// missing types are checked without factory write lock, loggers call it on every event
+	if !hasBackendType(name) {
+		return nil, false
+	}
+
+	factory.mutex.Lock()
+	defer factory.mutex.Unlock()
+
+	if backend, ok := factory.backend[name]; ok {
+		return backend, true
+	}
+
+	if err := factory.declareBackend(name, name, nil); err != nil {
+		return nil, false
+	}
+
+	return factory.backend[name], true
+}
@@ -0,0 +1,64 @@
+package slf4go
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/scf4go"
+)
+
+// size units, all units are powers of 1024
+var sizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1 << 10,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1 << 20,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1 << 30,
+	"gib": 1 << 30,
+	"t":   1 << 40,
+	"tb":  1 << 40,
+	"tib": 1 << 40,
+}
+
+// ParseSize parse human readable size like '100MB', '1.5GiB' or '4096', units are case insensitive
+// and powers of 1024
+func ParseSize(text string) (int64, error) {
+	text = strings.TrimSpace(text)
+
+	end := 0
+
+	for end < len(text) && (text[end] >= '0' && text[end] <= '9' || text[end] == '.') {
+		end++
+	}
+
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(text[end:]))]
+
+	if !ok {
+		return 0, errors.Wrap(ErrArgs, "unknown unit of size %s", text)
+	}
+
+	value, err := strconv.ParseFloat(text[:end], 64)
+
+	if err != nil || value < 0 {
+		return 0, errors.Wrap(ErrArgs, "invalid size %s", text)
+	}
+
+	return int64(value * float64(unit)), nil
+}
+
+// ConfigSize read size of config value, the value is either bytes count or a string parsed by ParseSize
+func ConfigSize(value scf4go.Value, def int64) (int64, error) {
+	text := value.String("")
+
+	if text == "" {
+		return int64(value.Int(int(def))), nil
+	}
+
+	return ParseSize(text)
+}
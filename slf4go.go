@@ -1,8 +1,10 @@
 package slf4go
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 
 	"regexp"
 	"strings"
@@ -19,6 +21,7 @@ const errVendor = "slf4go"
 var (
 	ErrArgs  = errors.New("args number errors", errors.WithVendor(errVendor), errors.WithCode(-1))
 	ErrLevel = errors.New("invalid error level", errors.WithVendor(errVendor), errors.WithCode(-2))
+	ErrType  = errors.New("backend or filter type not found", errors.WithVendor(errVendor), errors.WithCode(-3))
 )
 
 // Logger .
@@ -31,6 +34,36 @@ type Logger interface {
 	E(message string, args ...interface{})
 }
 
+// EntryLogger optional Logger interface used by adapters sending pre-built events,
+// loggers created by Factory implement it
+type EntryLogger interface {
+	Logger
+	// Enabled check if events with level are delivered to backend
+	Enabled(level Level) bool
+	// Send deliver event to backend if its level is enabled, empty Source is set to logger name
+	// and empty EventType is computed from Template
+	Send(entry *EventEntry)
+}
+
+// LogAt write message through the Logger level method of level, used by adapters when logger
+// does not implement EntryLogger. Placeholders are broken so the message is written as is
+func LogAt(logger Logger, level Level, message string) {
+	message = strings.Replace(message, "{@", "{ @", -1)
+
+	switch level {
+	case TRACE:
+		logger.T(message)
+	case DEBUG:
+		logger.D(message)
+	case INFO:
+		logger.I(message)
+	case WARN:
+		logger.W(message)
+	default:
+		logger.E(message)
+	}
+}
+
 // Backend .
 type Backend interface {
 	Config(config scf4go.Config) error
@@ -38,6 +71,60 @@ type Backend interface {
 	Sync()
 }
 
+// Flusher optional Backend interface, drain pending events and keep the backend working
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// BatchBackend optional Backend interface, receive events in batches from asynchronous filters
+type BatchBackend interface {
+	SendBatch(entries []*EventEntry)
+}
+
+// SendBatch send events with BatchBackend.SendBatch, fallback to Backend.Send one by one
+// if the backend does not implement BatchBackend
+func SendBatch(backend Backend, entries []*EventEntry) {
+	if batch, ok := backend.(BatchBackend); ok {
+		batch.SendBatch(entries)
+		return
+	}
+
+	for _, entry := range entries {
+		backend.Send(entry)
+	}
+}
+
+// BackendStats backend event statistics
+type BackendStats struct {
+	Enqueued uint64 `json:"enqueued"` // events accepted by asynchronous queue
+	Dropped  uint64 `json:"dropped"`  // events dropped by overflow policy or after close
+	Depth    int    `json:"depth"`    // events waiting for delivery
+	Written  uint64 `json:"written"`  // bytes written to the sink
+	Failed   uint64 `json:"failed"`   // events failed to write to the sink
+}
+
+// Add merge statistics of the wrapped backend
+func (stats BackendStats) Add(other BackendStats) BackendStats {
+	return BackendStats{
+		Enqueued: stats.Enqueued + other.Enqueued,
+		Dropped:  stats.Dropped + other.Dropped,
+		Depth:    stats.Depth + other.Depth,
+		Written:  stats.Written + other.Written,
+		Failed:   stats.Failed + other.Failed,
+	}
+}
+
+// StatsBackend optional Backend interface reporting statistics
+type StatsBackend interface {
+	Stats() BackendStats
+}
+
+// Closer optional Backend/Filter interface, drain pending events and release resources.
+// Backends created by Filter.MakeChain must close the wrapped backend after draining
+type Closer interface {
+	Close(ctx context.Context) error
+}
+
 // Filter .
 type Filter interface {
 	Name() string
@@ -89,25 +176,34 @@ func (l *Level) UnmarshalJSON(b []byte) error {
 	if err := json.Unmarshal(b, &s); err != nil {
 		return err
 	}
+
+	level, err := ParseLevel(s)
+
+	if err != nil {
+		return err
+	}
+
+	*l = level
+
+	return nil
+}
+
+// ParseLevel parse level name case-insensitively
+func ParseLevel(s string) (Level, error) {
 	switch strings.ToLower(s) {
 	case "trace":
-		*l = TRACE
-		return nil
+		return TRACE, nil
 	case "debug":
-		*l = DEBUG
-		return nil
+		return DEBUG, nil
 	case "info":
-		*l = INFO
-		return nil
+		return INFO, nil
 	case "warn":
-		*l = WARN
-		return nil
+		return WARN, nil
 	case "error":
-		*l = ERROR
-		return nil
+		return ERROR, nil
 	}
 
-	return errors.Wrap(ErrLevel, "unknown level %s", s)
+	return TRACE, errors.Wrap(ErrLevel, "unknown level %s", s)
 }
 
 // logger levels .
@@ -124,6 +220,8 @@ type EventEntry struct {
 	Timestamp time.Time              `json:"@t"`
 	Level     Level                  `json:"@l"`
 	Message   string                 `json:"@m"`
+	Template  string                 `json:"@mt,omitempty"` // message template before rendering
+	EventType string                 `json:"@i,omitempty"`  // hash of Template, see EventTypeOf
 	Attrs     map[string]interface{} `json:"@a"`
 	Source    string                 `json:"@s"`
 	File      string                 `json:"@f"`
@@ -131,13 +229,28 @@ type EventEntry struct {
 	Function  string                 `json:"@func"`
 }
 
-type loggerFactory struct {
-	sync.RWMutex
-	filter        []Filter
-	backend       map[string]Backend
+// EventTypeOf compute stable event type of message template, events logged by the same
+// statement share the event type whatever the argument values are
+func EventTypeOf(template string) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(template))
+
+	return fmt.Sprintf("%08x", hash.Sum32())
+}
+
+// Factory logger factory holding backends, filters and loggers configuration,
+// package-level functions use the Default factory
+type Factory struct {
+	mutex         sync.RWMutex
+	filter        []Filter                    // filters wrapping backends without filters config
+	filters       map[string]Filter           // all filters indexed by name
+	raw           map[string]*backendInstance // backends before wrapped by filters
+	backend       map[string]Backend          // filter chains indexed by backend name
 	configs       map[string]*loggerConfig
 	loggers       map[string]*loggerFacade
 	defaultConfig *loggerConfig
+	closed        bool
+	metrics       *metricsRegistry
 }
 
 type loggerConfig struct {
@@ -145,14 +258,19 @@ type loggerConfig struct {
 	Level   Level  `json:"level"`
 }
 
-func newLoggerFactory() *loggerFactory {
-	factory := &loggerFactory{
+// New create isolated logger factory with only the null backend registered
+func New() *Factory {
+	factory := &Factory{
+		filters: make(map[string]Filter),
+		raw:     make(map[string]*backendInstance),
 		backend: make(map[string]Backend),
 		configs: make(map[string]*loggerConfig),
 		loggers: make(map[string]*loggerFacade),
+		metrics: newMetricsRegistry(),
 	}
 
-	factory.backend["null"] = &nullBackend{}
+	factory.raw["null"] = &backendInstance{backend: &nullBackend{}}
+	factory.backend["null"] = factory.raw["null"].backend
 
 	factory.defaultConfig = &loggerConfig{
 		Level:   DEBUG,
@@ -162,26 +280,41 @@ func newLoggerFactory() *loggerFactory {
 	return factory
 }
 
-func (factory *loggerFactory) registerBackend(name string, backend Backend) {
-	factory.Lock()
-	defer factory.Unlock()
+// RegisterBackend register backend with name, the backend is wrapped by registered filters
+func (factory *Factory) RegisterBackend(name string, backend Backend) {
+	factory.mutex.Lock()
+	defer factory.mutex.Unlock()
 
-	for _, filter := range factory.filter {
-		backend = filter.MakeChain(backend)
-	}
+	instance := &backendInstance{backend: backend}
 
-	factory.backend[name] = backend
+	factory.raw[name] = instance
+	factory.backend[name] = factory.makeChain(instance)
 }
 
-func (factory *loggerFactory) registerFilter(filter Filter) {
-	factory.Lock()
-	defer factory.Unlock()
+// RegisterFilter create filter chain with call order
+func (factory *Factory) RegisterFilter(filter Filter) {
+	factory.mutex.Lock()
+	defer factory.mutex.Unlock()
 
 	factory.filter = append(factory.filter, filter)
+	factory.filters[filter.Name()] = filter
 
 	cached := make(map[string]Backend)
 
 	for name, backend := range factory.backend {
+		instance := factory.raw[name]
+
+		if instance.filters != nil {
+			cached[name] = backend
+			continue
+		}
+
+		if instance.base == nil {
+			// the chain is closed without closing the raw backend if it is rebuilt later
+			instance.base = &chainBase{backend: instance.backend}
+			backend = instance.base
+		}
+
 		println(fmt.Sprintf("filter %s backend %s", filter.Name(), name))
 		cached[name] = filter.MakeChain(backend)
 	}
@@ -189,9 +322,10 @@ func (factory *loggerFactory) registerFilter(filter Filter) {
 	factory.backend = cached
 }
 
-func (factory *loggerFactory) configLogger(logger string, backend string, level Level) {
-	factory.Lock()
-	defer factory.Unlock()
+// ConfigLogger set logger backend and level
+func (factory *Factory) ConfigLogger(logger string, backend string, level Level) {
+	factory.mutex.Lock()
+	defer factory.mutex.Unlock()
 
 	factory.configs[logger] = &loggerConfig{
 		Backend: backend,
@@ -199,9 +333,10 @@ func (factory *loggerFactory) configLogger(logger string, backend string, level
 	}
 }
 
-func (factory *loggerFactory) config(backend string, level Level) {
-	factory.Lock()
-	defer factory.Unlock()
+// ConfigDefault set backend and level of loggers without config
+func (factory *Factory) ConfigDefault(backend string, level Level) {
+	factory.mutex.Lock()
+	defer factory.mutex.Unlock()
 
 	factory.defaultConfig = &loggerConfig{
 		Backend: backend,
@@ -209,27 +344,123 @@ func (factory *loggerFactory) config(backend string, level Level) {
 	}
 }
 
-func (factory *loggerFactory) sync() {
-	factory.Lock()
-	defer factory.Unlock()
-
-	for _, backend := range factory.backend {
-		backend.Sync()
+// Sync sync flush all logger event
+func (factory *Factory) Sync() {
+	if err := factory.Flush(context.Background()); err != nil {
+		println(fmt.Sprintf("sync backends error: %s", err))
 	}
 }
 
-func (factory *loggerFactory) setConfig(config scf4go.Config) error {
-	factory.Lock()
-	defer factory.Unlock()
+func (factory *Factory) chains() map[string]Backend {
+	factory.mutex.RLock()
+	defer factory.mutex.RUnlock()
+
+	chains := make(map[string]Backend, len(factory.backend))
 
 	for name, backend := range factory.backend {
-		backend.Config(config.SubConfig("backend", name))
+		chains[name] = backend
+	}
+
+	return chains
+}
+
+// Stats get statistics of backends implementing StatsBackend indexed by backend name
+func (factory *Factory) Stats() map[string]BackendStats {
+	stats := make(map[string]BackendStats)
+
+	for name, backend := range factory.chains() {
+		if reporter, ok := backend.(StatsBackend); ok {
+			stats[name] = reporter.Stats()
+		}
+	}
+
+	return stats
+}
+
+// Flush drain all backends pending events and keep them working
+func (factory *Factory) Flush(ctx context.Context) error {
+	var lastErr error
+
+	for name, backend := range factory.chains() {
+		if err := FlushBackend(ctx, backend); err != nil {
+			lastErr = errors.Wrap(err, "flush backend %s error", name)
+		}
+	}
+
+	return lastErr
+}
+
+// Shutdown drain and close all filters and backends, loggers are disabled after shutdown
+func (factory *Factory) Shutdown(ctx context.Context) error {
+	factory.mutex.Lock()
+
+	if factory.closed {
+		factory.mutex.Unlock()
+		return nil
+	}
+
+	factory.closed = true
+	filters := factory.filter
+
+	factory.mutex.Unlock()
+
+	var lastErr error
+
+	// close filter chains first, each chain closes the wrapped backend after draining
+	for name, backend := range factory.chains() {
+		if err := CloseBackend(ctx, backend); err != nil {
+			lastErr = errors.Wrap(err, "close backend %s error", name)
+		}
+	}
+
+	for _, filter := range filters {
+		if closer, ok := filter.(Closer); ok {
+			if err := closer.Close(ctx); err != nil {
+				lastErr = errors.Wrap(err, "close filter %s error", filter.Name())
+			}
+		}
 	}
 
-	for _, filter := range factory.filter {
+	return lastErr
+}
+
+// Config config loggers with scf4go
+func (factory *Factory) Config(config scf4go.Config) error {
+	factory.mutex.Lock()
+	defer factory.mutex.Unlock()
+
+	filters, err := sectionNames(config, "filter")
+
+	if err != nil {
+		return err
+	}
+
+	for _, name := range filters {
+		// sections of types not linked into the binary are ignored
+		if err := factory.declareFilter(name, config.Get("filter", name, "type").String(name)); err != nil {
+			println(fmt.Sprintf("ignore filter section: %s", err))
+		}
+	}
+
+	for _, filter := range factory.filters {
 		filter.Config(config.SubConfig("filter", filter.Name()))
 	}
 
+	backends, err := sectionNames(config, "backend")
+
+	if err != nil {
+		return err
+	}
+
+	for _, name := range backends {
+		typeName := config.Get("backend", name, "type").String(name)
+		filters := config.Get("backend", name, "filters").StringSlice(nil)
+
+		if err := factory.declareBackend(name, typeName, filters); err != nil {
+			println(fmt.Sprintf("ignore backend section: %s", err))
+		}
+	}
+
 	var defaultConfig loggerConfig
 
 	if err := config.Get("default").Scan(&defaultConfig); err != nil {
@@ -246,13 +477,41 @@ func (factory *loggerFactory) setConfig(config scf4go.Config) error {
 
 	factory.configs = configs
 
+	// create undeclared backends referred by loggers with the type of the same name
+	for _, logger := range append([]*loggerConfig{&defaultConfig}, mapValues(configs)...) {
+		if _, ok := factory.raw[logger.Backend]; ok || logger.Backend == "" {
+			continue
+		}
+
+		if err := factory.declareBackend(logger.Backend, logger.Backend, nil); err != nil {
+			println(fmt.Sprintf("logger backend '%s' not found", logger.Backend))
+		}
+	}
+
+	for name, backend := range factory.backend {
+		if err := backend.Config(config.SubConfig("backend", name)); err != nil {
+			return errors.Wrap(err, "config backend %s error", name)
+		}
+	}
+
 	return nil
 }
 
-func (factory *loggerFactory) createLogger(name string) Logger {
+func mapValues(configs map[string]*loggerConfig) []*loggerConfig {
+	var values []*loggerConfig
+
+	for _, config := range configs {
+		values = append(values, config)
+	}
+
+	return values
+}
+
+// Get create or get logger with name
+func (factory *Factory) Get(name string) Logger {
 
-	factory.Lock()
-	defer factory.Unlock()
+	factory.mutex.Lock()
+	defer factory.mutex.Unlock()
 
 	logger, ok := factory.loggers[name]
 
@@ -266,9 +525,13 @@ func (factory *loggerFactory) createLogger(name string) Logger {
 	return logger
 }
 
-func (factory *loggerFactory) getBackend(name string) (Backend, Level) {
-	factory.RLock()
-	defer factory.RUnlock()
+func (factory *Factory) getBackend(name string) (string, Backend, Level) {
+	factory.mutex.RLock()
+
+	if factory.closed {
+		factory.mutex.RUnlock()
+		return "", nil, ERROR
+	}
 
 	config, ok := factory.configs[name]
 
@@ -278,19 +541,25 @@ func (factory *loggerFactory) getBackend(name string) (Backend, Level) {
 
 	backend, ok := factory.backend[config.Backend]
 
+	factory.mutex.RUnlock()
+
+	if !ok {
+		backend, ok = factory.lazyBackend(config.Backend)
+	}
+
 	if !ok {
 		println(fmt.Sprintf("logger '%s' backend '%s' not found", name, config.Backend))
 	}
 
-	return backend, config.Level
+	return config.Backend, backend, config.Level
 }
 
 type loggerFacade struct {
-	factory *loggerFactory
+	factory *Factory
 	name    string
 }
 
-func newLoggerFacade(name string, factory *loggerFactory) *loggerFacade {
+func newLoggerFacade(name string, factory *Factory) *loggerFacade {
 	return &loggerFacade{
 		factory: factory,
 		name:    name,
@@ -301,26 +570,55 @@ func (facade *loggerFacade) Name() string {
 	return facade.name
 }
 
-func (facade *loggerFacade) process(wl Level) (Backend, bool) {
+func (facade *loggerFacade) process(wl Level) (string, Backend, bool) {
 
-	backend, level := facade.factory.getBackend(facade.name)
+	name, backend, level := facade.factory.getBackend(facade.name)
 
 	if backend == nil {
-		return nil, false
+		return "", nil, false
 	}
 
 	if level > wl {
 
-		return nil, false
+		return "", nil, false
 	}
 
-	return backend, true
+	return name, backend, true
+}
+
+func (facade *loggerFacade) send(name string, backend Backend, entry *EventEntry) {
+	start := time.Now()
+
+	backend.Send(entry)
+
+	facade.factory.metrics.record(entry.Level, facade.name, name, time.Since(start))
 }
 
 var messageRegx = regexp.MustCompile(`{@[a-zA-Z0-9]*}`)
 
+// formatArg render placeholder argument
+func formatArg(arg interface{}) (string, error) {
+	if val, ok := arg.(error); ok {
+		return val.Error(), nil
+	}
+
+	if val, ok := arg.(fmt.Stringer); ok {
+		return val.String(), nil
+	}
+
+	buff, err := json.Marshal(arg)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(buff), nil
+}
+
 func (facade *loggerFacade) createEventEntry(message string, level Level, args ...interface{}) *EventEntry {
 
+	template := message
+
 	placeholders := messageRegx.FindAllString(message, -1)
 
 	if len(placeholders) != len(args) {
@@ -331,21 +629,10 @@ func (facade *loggerFacade) createEventEntry(message string, level Level, args .
 
 	for i, placeholder := range placeholders {
 
-		var data string
-
-		if val, ok := args[i].(error); ok {
-			data = val.Error()
-		} else if val, ok := args[i].(fmt.Stringer); ok {
-			data = val.String()
-		} else {
+		data, err := formatArg(args[i])
 
-			buff, err := json.Marshal(args[i])
-
-			if err != nil {
-				panic(errors.Wrap(err, "marshal arg %d error", i))
-			}
-
-			data = string(buff)
+		if err != nil {
+			panic(errors.Wrap(err, "marshal arg %d error", i))
 		}
 
 		message = strings.Replace(message, placeholder, data, -1)
@@ -361,6 +648,8 @@ func (facade *loggerFacade) createEventEntry(message string, level Level, args .
 		Timestamp: time.Now(),
 		Level:     level,
 		Message:   message,
+		Template:  template,
+		EventType: EventTypeOf(template),
 		Attrs:     attrs,
 		Source:    facade.name,
 		File:      callframe.File,
@@ -371,32 +660,52 @@ func (facade *loggerFacade) createEventEntry(message string, level Level, args .
 	return entry
 }
 
+func (facade *loggerFacade) Enabled(level Level) bool {
+	_, _, ok := facade.process(level)
+
+	return ok
+}
+
+func (facade *loggerFacade) Send(entry *EventEntry) {
+	if entry.Source == "" {
+		entry.Source = facade.name
+	}
+
+	if entry.EventType == "" && entry.Template != "" {
+		entry.EventType = EventTypeOf(entry.Template)
+	}
+
+	if name, backend, ok := facade.process(entry.Level); ok {
+		facade.send(name, backend, entry)
+	}
+}
+
 func (facade *loggerFacade) T(message string, args ...interface{}) {
-	if backend, ok := facade.process(TRACE); ok {
-		backend.Send(facade.createEventEntry(message, TRACE, args...))
+	if name, backend, ok := facade.process(TRACE); ok {
+		facade.send(name, backend, facade.createEventEntry(message, TRACE, args...))
 	}
 }
 
 func (facade *loggerFacade) D(message string, args ...interface{}) {
-	if backend, ok := facade.process(DEBUG); ok {
-		backend.Send(facade.createEventEntry(message, DEBUG, args...))
+	if name, backend, ok := facade.process(DEBUG); ok {
+		facade.send(name, backend, facade.createEventEntry(message, DEBUG, args...))
 	}
 }
 
 func (facade *loggerFacade) I(message string, args ...interface{}) {
-	if backend, ok := facade.process(INFO); ok {
-		backend.Send(facade.createEventEntry(message, INFO, args...))
+	if name, backend, ok := facade.process(INFO); ok {
+		facade.send(name, backend, facade.createEventEntry(message, INFO, args...))
 	}
 }
 
 func (facade *loggerFacade) W(message string, args ...interface{}) {
-	if backend, ok := facade.process(WARN); ok {
-		backend.Send(facade.createEventEntry(message, WARN, args...))
+	if name, backend, ok := facade.process(WARN); ok {
+		facade.send(name, backend, facade.createEventEntry(message, WARN, args...))
 	}
 }
 
 func (facade *loggerFacade) E(message string, args ...interface{}) {
-	if backend, ok := facade.process(ERROR); ok {
-		backend.Send(facade.createEventEntry(message, ERROR, args...))
+	if name, backend, ok := facade.process(ERROR); ok {
+		facade.send(name, backend, facade.createEventEntry(message, ERROR, args...))
 	}
 }
@@ -1,16 +1,19 @@
 package slf4go
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/libs4go/scf4go"
 	_ "github.com/libs4go/scf4go/codec" //
 	"github.com/libs4go/scf4go/reader/file"
+	"github.com/libs4go/scf4go/reader/memory"
 	"github.com/stretchr/testify/require"
 )
 
@@ -38,17 +41,18 @@ func TestRegex(t *testing.T) {
 }
 
 func TestLog(t *testing.T) {
+	factory := New()
 	mock := &mockBackend{}
-	getLoggerFactor().config("mock", DEBUG)
-	RegisterBackend("mock", mock)
+	factory.ConfigDefault("mock", DEBUG)
+	factory.RegisterBackend("mock", mock)
 
-	logger := Get("test")
+	logger := factory.Get("test")
 
 	logger.D("test one {@one}", 1)
 
 	require.Equal(t, 1, len(mock.events))
 
-	getLoggerFactor().config("mock", INFO)
+	factory.ConfigDefault("mock", INFO)
 
 	logger.D("test one {@one}", 1)
 
@@ -78,18 +82,32 @@ func TestConfig(t *testing.T) {
 
 	err := config.Load(file.New(file.Yaml("./slf4go.yaml")))
 	require.NoError(t, err)
+	factory := New()
 	mock := &mockBackend{}
-	RegisterBackend("mock", mock)
-	err = Config(config)
+	factory.RegisterBackend("mock", mock)
+	err = factory.Config(config)
 	require.NoError(t, err)
 
-	require.Equal(t, getLoggerFactor().defaultConfig.Level, INFO)
-	require.Equal(t, getLoggerFactor().defaultConfig.Backend, "mock")
+	require.Equal(t, factory.defaultConfig.Level, INFO)
+	require.Equal(t, factory.defaultConfig.Backend, "mock")
 
 	require.Equal(t, mock.config.Get("test").String(""), "salt")
 
-	require.Equal(t, getLoggerFactor().configs["test"].Backend, "test")
-	require.Equal(t, getLoggerFactor().configs["test"].Level, ERROR)
+	require.Equal(t, factory.configs["test"].Backend, "test")
+	require.Equal(t, factory.configs["test"].Level, ERROR)
+}
+
+func TestDefault(t *testing.T) {
+	mock := &mockBackend{}
+	RegisterBackend("default-mock", mock)
+	Default().ConfigLogger("default-test", "default-mock", DEBUG)
+
+	Get("default-test").D("test one {@one}", 1)
+
+	require.Equal(t, 1, len(mock.events))
+
+	_, ok := New().chains()["default-mock"]
+	require.False(t, ok)
 }
 
 func TestFilepath(t *testing.T) {
@@ -98,3 +116,452 @@ func TestFilepath(t *testing.T) {
 		return nil
 	})
 }
+
+type closableBackend struct {
+	mockBackend
+	closed bool
+}
+
+func (closable *closableBackend) Close(ctx context.Context) error {
+	closable.closed = true
+	return nil
+}
+
+func TestShutdown(t *testing.T) {
+	factory := New()
+
+	mock := &closableBackend{}
+	factory.RegisterBackend("mock", mock)
+	factory.ConfigDefault("mock", DEBUG)
+
+	logger := factory.Get("test")
+
+	logger.D("test one {@one}", 1)
+
+	require.NoError(t, factory.Flush(context.Background()))
+	require.Equal(t, 1, len(mock.events))
+
+	require.NoError(t, factory.Shutdown(context.Background()))
+	require.True(t, mock.closed)
+
+	logger.D("test one {@one}", 1)
+
+	require.Equal(t, 1, len(mock.events))
+}
+
+func TestMetrics(t *testing.T) {
+	factory := New()
+
+	mock := &mockBackend{}
+	factory.RegisterBackend("mock", mock)
+	factory.ConfigDefault("mock", DEBUG)
+
+	logger := factory.Get("test")
+
+	logger.D("test one {@one}", 1)
+	logger.D("test one {@one}", 1)
+	logger.E("test one {@one}", 1)
+	logger.T("test one {@one}", 1)
+
+	metrics := factory.CollectMetrics()
+
+	require.Equal(t, []EventMetrics{
+		{Level: DEBUG, Logger: "test", Backend: "mock", Count: 2},
+		{Level: ERROR, Logger: "test", Backend: "mock", Count: 1},
+	}, metrics.Events)
+
+	require.Equal(t, uint64(3), metrics.Backends["mock"].Latency.Count)
+	require.Equal(t, uint64(3), metrics.Backends["mock"].Latency.Buckets[len(LatencyBuckets)-1])
+}
+
+type namedMockBackend struct {
+	mockBackend
+	name string
+}
+
+type tagFilter struct {
+	name string
+	tag  string
+}
+
+func (filter *tagFilter) Name() string {
+	return filter.name
+}
+
+func (filter *tagFilter) Config(config scf4go.Config) {
+	filter.tag = config.Get("tag").String(filter.name)
+}
+
+func (filter *tagFilter) MakeChain(backend Backend) Backend {
+	return &tagBackend{Backend: backend, filter: filter}
+}
+
+type tagBackend struct {
+	Backend
+	filter *tagFilter
+	closed bool
+}
+
+func (tag *tagBackend) Send(entry *EventEntry) {
+	entry.Message = tag.filter.tag + ":" + entry.Message
+	tag.Backend.Send(entry)
+}
+
+func (tag *tagBackend) Close(ctx context.Context) error {
+	tag.closed = true
+	return CloseBackend(ctx, tag.Backend)
+}
+
+type closingMockBackend struct {
+	mockBackend
+	closed bool
+}
+
+func (mock *closingMockBackend) Close(ctx context.Context) error {
+	mock.closed = true
+	return nil
+}
+
+func TestRebuildChain(t *testing.T) {
+	restoreTypes(t)
+
+	var tags []*tagBackend
+
+	RegisterFilterType("tag", func(name string) Filter {
+		return &tagFilter{name: name}
+	})
+
+	factory := New()
+
+	backend := &closingMockBackend{}
+	factory.RegisterBackend("app", backend)
+
+	for _, filters := range []string{"[a]", "[b]"} {
+		config := scf4go.New()
+
+		err := config.Load(memory.New(memory.Data(fmt.Sprintf(`
+default:
+  backend: app
+  level: debug
+backend:
+  app:
+    filters: %s
+filter:
+  a:
+    type: tag
+  b:
+    type: tag
+`, filters), "yaml")))
+		require.NoError(t, err)
+
+		require.NoError(t, factory.Config(config))
+
+		tags = append(tags, factory.chains()["app"].(*tagBackend))
+	}
+
+	// the replaced chain is closed, the shared backend is not
+	require.True(t, tags[0].closed)
+	require.False(t, tags[1].closed)
+	require.False(t, backend.closed)
+
+	factory.Get("test").I("event")
+	require.Equal(t, "b:event", backend.events[0].Message)
+
+	require.NoError(t, factory.Shutdown(context.Background()))
+	require.True(t, backend.closed)
+}
+
+// restoreTypes restore global type registries after test
+func restoreTypes(t *testing.T) {
+	typesMutex.Lock()
+	defer typesMutex.Unlock()
+
+	savedBackends := backendTypes
+	savedFilters := filterTypes
+
+	backendTypes = make(map[string]BackendConstructor, len(savedBackends))
+	filterTypes = make(map[string]FilterConstructor, len(savedFilters))
+
+	for name, constructor := range savedBackends {
+		backendTypes[name] = constructor
+	}
+
+	for name, constructor := range savedFilters {
+		filterTypes[name] = constructor
+	}
+
+	t.Cleanup(func() {
+		typesMutex.Lock()
+		defer typesMutex.Unlock()
+
+		backendTypes = savedBackends
+		filterTypes = savedFilters
+	})
+}
+
+func TestBackendTypes(t *testing.T) {
+	restoreTypes(t)
+
+	instances := make(map[string]*namedMockBackend)
+
+	RegisterBackendType("mocktype", func(name string) Backend {
+		instances[name] = &namedMockBackend{name: name}
+		return instances[name]
+	})
+
+	RegisterFilterType("tag", func(name string) Filter {
+		return &tagFilter{name: name}
+	})
+
+	config := scf4go.New()
+
+	err := config.Load(memory.New(memory.Data(`
+default:
+  backend: app
+  level: debug
+logger:
+  audit:
+    backend: audit
+    level: info
+  lazy:
+    backend: mocktype
+    level: info
+backend:
+  app:
+    type: mocktype
+    path: ./app
+  audit:
+    type: mocktype
+    path: ./audit
+    filters: [audit_tag]
+filter:
+  audit_tag:
+    type: tag
+    tag: AUDIT
+`, "yaml")))
+	require.NoError(t, err)
+
+	factory := New()
+	require.NoError(t, factory.Config(config))
+
+	require.Equal(t, 3, len(instances))
+	require.Equal(t, "./app", instances["app"].config.Get("path").String(""))
+	require.Equal(t, "./audit", instances["audit"].config.Get("path").String(""))
+
+	factory.Get("test").I("app event")
+	factory.Get("audit").I("audit event")
+	factory.Get("lazy").I("lazy event")
+
+	require.Equal(t, "app event", instances["app"].events[0].Message)
+	require.Equal(t, "AUDIT:audit event", instances["audit"].events[0].Message)
+	require.Equal(t, "lazy event", instances["mocktype"].events[0].Message)
+
+	// sections of unknown types are ignored
+	err = config.Load(memory.New(memory.Data(`
+backend:
+  unknown:
+    type: unknown
+filter:
+  unknown:
+    type: unknown
+`, "yaml")))
+	require.NoError(t, err)
+
+	factory = New()
+	require.NoError(t, factory.Config(config))
+	require.NotContains(t, factory.raw, "unknown")
+	require.NotContains(t, factory.filters, "unknown")
+}
+
+type plainLogger struct {
+	messages []string
+}
+
+func (plain *plainLogger) Name() string { return "plain" }
+
+func (plain *plainLogger) T(message string, args ...interface{}) { plain.log(message, args) }
+func (plain *plainLogger) D(message string, args ...interface{}) { plain.log(message, args) }
+func (plain *plainLogger) I(message string, args ...interface{}) { plain.log(message, args) }
+func (plain *plainLogger) W(message string, args ...interface{}) { plain.log(message, args) }
+func (plain *plainLogger) E(message string, args ...interface{}) { plain.log(message, args) }
+
+func (plain *plainLogger) log(message string, args []interface{}) {
+	if len(messageRegx.FindAllString(message, -1)) != len(args) {
+		panic(ErrArgs)
+	}
+
+	plain.messages = append(plain.messages, message)
+}
+
+func TestSugar(t *testing.T) {
+	factory := New()
+	mock := &mockBackend{}
+	factory.RegisterBackend("mock", mock)
+	factory.ConfigDefault("mock", DEBUG)
+
+	sugar := Sugar(factory.Get("sugar"))
+
+	sugar.If("user %s {@id}", "bob")
+	sugar.Tf("hidden %d", 1)
+	sugar.Ww("user {@name} quota {@quota}", "name", "bob", "quota", 10, 42, "dangling")
+	sugar.Ew("odd", "key")
+
+	require.Equal(t, 3, len(mock.events))
+
+	require.Equal(t, "user bob {@id}", mock.events[0].Message)
+	require.Equal(t, INFO, mock.events[0].Level)
+	require.Equal(t, "sugar", mock.events[0].Source)
+	require.Equal(t, "github.com/libs4go/slf4go.TestSugar", mock.events[0].Function)
+
+	require.Equal(t, "user \"bob\" quota 10", mock.events[1].Message)
+	require.Equal(t, map[string]interface{}{
+		"@name":     "bob",
+		"@quota":    10,
+		"@!BADKEY":  42,
+		"@!BADKEY1": "dangling",
+	}, mock.events[1].Attrs)
+
+	require.Equal(t, map[string]interface{}{"@!BADKEY": "key"}, mock.events[2].Attrs)
+
+	plain := &plainLogger{}
+
+	Sugar(plain).Iw("value {@v}", "v", 1)
+	Sugar(plain).Df("value {@v} %d", 1)
+
+	require.Equal(t, []string{"value 1", "value { @v} 1"}, plain.messages)
+
+	LogAt(plain, ERROR, "raw {@v}")
+	require.Equal(t, "raw { @v}", plain.messages[2])
+}
+
+func TestEventType(t *testing.T) {
+	factory := New()
+	mock := &mockBackend{}
+	factory.RegisterBackend("mock", mock)
+	factory.ConfigDefault("mock", DEBUG)
+
+	logger := factory.Get("test")
+
+	for i := 0; i < 2; i++ {
+		logger.I("user {@id} login", i)
+	}
+
+	logger.I("user {@id} logout", 1)
+
+	Sugar(logger).Iw("user {@id} login", "id", 3)
+
+	require.Equal(t, "user {@id} login", mock.events[0].Template)
+	require.Equal(t, "user 0 login", mock.events[0].Message)
+	require.Equal(t, mock.events[0].EventType, mock.events[1].EventType)
+	require.NotEqual(t, mock.events[0].EventType, mock.events[2].EventType)
+	require.Equal(t, mock.events[0].EventType, mock.events[3].EventType)
+	require.Equal(t, 8, len(mock.events[0].EventType))
+
+	buff, err := json.Marshal(mock.events[0])
+	require.NoError(t, err)
+
+	var clef map[string]interface{}
+	require.NoError(t, json.Unmarshal(buff, &clef))
+
+	require.Equal(t, "user {@id} login", clef["@mt"])
+	require.Equal(t, mock.events[0].EventType, clef["@i"])
+	require.Equal(t, "user 0 login", clef["@m"])
+}
+
+func TestFormatter(t *testing.T) {
+	config := scf4go.New()
+
+	err := config.Load(memory.New(memory.Data(`
+json:
+  format: json
+clef:
+  format: clef
+logfmt:
+  format: logfmt
+  timestamp: "2006-01-02"
+text:
+  format: text
+  timestamp: "15:04:05"
+  template: '{{time .Timestamp}} {{lower .Level.String}} {{attr . "id"}} {{.Message}}'
+unknown:
+  format: unknown
+`, "yaml")))
+
+	require.NoError(t, err)
+
+	entry := &EventEntry{
+		Timestamp: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Level:     WARN,
+		Message:   "user 1 login failed",
+		Template:  "user {@id} login failed",
+		EventType: EventTypeOf("user {@id} login failed"),
+		Source:    "auth",
+		Attrs: map[string]interface{}{
+			"@id":  1,
+			"@err": fmt.Errorf("bad password"),
+		},
+	}
+
+	format := func(name string) string {
+		formatter, err := NewFormatter(config.SubConfig(name), "json")
+		require.NoError(t, err)
+
+		buff, err := formatter.Format(entry)
+		require.NoError(t, err)
+
+		return string(buff)
+	}
+
+	var decoded map[string]interface{}
+
+	require.NoError(t, json.Unmarshal([]byte(format("json")), &decoded))
+	require.Equal(t, "user 1 login failed", decoded["@m"])
+
+	decoded = nil
+
+	require.NoError(t, json.Unmarshal([]byte(format("clef")), &decoded))
+	require.Equal(t, "Warning", decoded["@l"])
+	require.Equal(t, "user {@id} login failed", decoded["@mt"])
+	require.Equal(t, "bad password", decoded["@x"])
+	require.Equal(t, float64(1), decoded["id"])
+	require.Equal(t, "auth", decoded["SourceContext"])
+
+	require.Equal(t, `time=2020-01-02 level=warn logger=auth msg="user 1 login failed" err="bad password" id=1`, format("logfmt"))
+
+	require.Equal(t, "03:04:05 warn 1 user 1 login failed", format("text"))
+
+	_, err = NewFormatter(config.SubConfig("unknown"), "json")
+	require.Error(t, err)
+
+	RegisterFormatter("message", func(config scf4go.Config) (Formatter, error) {
+		return FormatFunc(func(entry *EventEntry) ([]byte, error) {
+			return []byte(entry.Message), nil
+		}), nil
+	})
+
+	formatter, err := NewFormatter(config.SubConfig("missing"), "message")
+	require.NoError(t, err)
+
+	buff, err := formatter.Format(entry)
+	require.NoError(t, err)
+	require.Equal(t, "user 1 login failed", string(buff))
+}
+
+func TestParseSize(t *testing.T) {
+	for text, expected := range map[string]int64{
+		"4096":   4096,
+		"100MB":  100 << 20,
+		"1.5gib": 3 << 29,
+		"10 k":   10 << 10,
+		"2TB":    2 << 40,
+	} {
+		size, err := ParseSize(text)
+		require.NoError(t, err)
+		require.Equal(t, expected, size, text)
+	}
+
+	for _, text := range []string{"", "MB", "10XB", "-1"} {
+		_, err := ParseSize(text)
+		require.Error(t, err, text)
+	}
+}
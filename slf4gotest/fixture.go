@@ -0,0 +1,44 @@
+package slf4gotest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libs4go/scf4go"
+	_ "github.com/libs4go/scf4go/codec" // yaml codec
+	"github.com/libs4go/scf4go/reader/memory"
+	"github.com/libs4go/slf4go"
+)
+
+// LoadConfig load yaml config data for backend and filter tests, the test stops if data is invalid
+func LoadConfig(t testing.TB, data string) scf4go.Config {
+	t.Helper()
+
+	config := scf4go.New()
+
+	if err := config.Load(memory.New(memory.Data(data, "yaml"))); err != nil {
+		t.Fatalf("load config error: %s", err)
+	}
+
+	return config
+}
+
+// Entry build event entry of logger 'test' timestamped now, attributes are given as alternating
+// keys and values and stored with '@' prefix like loggers do
+func Entry(level slf4go.Level, message string, keysAndValues ...interface{}) *slf4go.EventEntry {
+	attrs := make(map[string]interface{}, len(keysAndValues)/2)
+
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		attrs["@"+strings.TrimPrefix(fmt.Sprint(keysAndValues[i]), "@")] = keysAndValues[i+1]
+	}
+
+	return &slf4go.EventEntry{
+		Timestamp: time.Now(),
+		Level:     level,
+		Message:   message,
+		Source:    "test",
+		Attrs:     attrs,
+	}
+}
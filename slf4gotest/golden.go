@@ -0,0 +1,75 @@
+package slf4gotest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/libs4go/slf4go"
+)
+
+// UpdateGoldenEnv set this environment variable to rewrite golden files with current output
+const UpdateGoldenEnv = "SLF4GOTEST_UPDATE_GOLDEN"
+
+// Format event formatter used by golden comparison
+type Format func(entry *slf4go.EventEntry) ([]byte, error)
+
+func formatStable(entry *slf4go.EventEntry) ([]byte, error) {
+	return []byte(FormatEntry(entry)), nil
+}
+
+// AssertGolden compare recorded events formatted by FormatEntry with golden file
+func (recorder *Recorder) AssertGolden(t testing.TB, path string) bool {
+	t.Helper()
+
+	return recorder.AssertGoldenWith(t, path, formatStable)
+}
+
+// AssertGoldenWith compare recorded events formatted one per line with golden file
+func (recorder *Recorder) AssertGoldenWith(t testing.TB, path string, format Format) bool {
+	t.Helper()
+
+	var buff bytes.Buffer
+
+	for _, entry := range recorder.Events() {
+		line, err := format(entry)
+
+		if err != nil {
+			t.Errorf("format event %s error: %s", FormatEntry(entry), err)
+			return false
+		}
+
+		buff.Write(bytes.TrimSuffix(line, []byte("\n")))
+		buff.WriteByte('\n')
+	}
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Errorf("create golden dir error: %s", err)
+			return false
+		}
+
+		if err := ioutil.WriteFile(path, buff.Bytes(), 0644); err != nil {
+			t.Errorf("write golden file %s error: %s", path, err)
+			return false
+		}
+
+		return true
+	}
+
+	expect, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		t.Errorf("read golden file %s error: %s, set %s=1 to create it", path, err, UpdateGoldenEnv)
+		return false
+	}
+
+	if !bytes.Equal(expect, buff.Bytes()) {
+		t.Errorf("output mismatch golden file %s\nexpect:\n%s\ngot:\n%s", path, expect, buff.Bytes())
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,279 @@
+// Package slf4gotest provides recording backend, isolated factories and log assertions for tests
+package slf4gotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/libs4go/scf4go"
+	"github.com/libs4go/slf4go"
+)
+
+// Recorder concurrency-safe backend recording all received events
+type Recorder struct {
+	mutex  sync.Mutex
+	events []*slf4go.EventEntry
+	tee    slf4go.Backend
+}
+
+// NewRecorder create recording backend
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// NewFactory create isolated factory logging all levels into a recorder,
+// which also writes events through t.Log so they appear only for failing tests
+func NewFactory(t testing.TB) (*slf4go.Factory, *Recorder) {
+	recorder := &Recorder{tee: NewTestingBackend(t)}
+
+	factory := slf4go.New()
+	factory.RegisterBackend("recorder", recorder)
+	factory.ConfigDefault("recorder", slf4go.TRACE)
+
+	return factory, recorder
+}
+
+// Send .
+func (recorder *Recorder) Send(entry *slf4go.EventEntry) {
+	recorder.mutex.Lock()
+	recorder.events = append(recorder.events, entry)
+	recorder.mutex.Unlock()
+
+	if recorder.tee != nil {
+		recorder.tee.Send(entry)
+	}
+}
+
+// Sync .
+func (recorder *Recorder) Sync() {
+}
+
+// Config .
+func (recorder *Recorder) Config(config scf4go.Config) error {
+	return nil
+}
+
+// Events get a copy of recorded events
+func (recorder *Recorder) Events() []*slf4go.EventEntry {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	events := make([]*slf4go.EventEntry, len(recorder.events))
+	copy(events, recorder.events)
+
+	return events
+}
+
+// Reset drop all recorded events
+func (recorder *Recorder) Reset() {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.events = nil
+}
+
+// Matcher event entry predicate
+type Matcher struct {
+	desc  string
+	match func(entry *slf4go.EventEntry) bool
+}
+
+// Match .
+func (matcher Matcher) Match(entry *slf4go.EventEntry) bool {
+	return matcher.match(entry)
+}
+
+func (matcher Matcher) String() string {
+	return matcher.desc
+}
+
+// Level match events with level
+func Level(level slf4go.Level) Matcher {
+	return Matcher{
+		desc: fmt.Sprintf("level=%s", level),
+		match: func(entry *slf4go.EventEntry) bool {
+			return entry.Level == level
+		},
+	}
+}
+
+// Message match events whose rendered message contains substr
+func Message(substr string) Matcher {
+	return Matcher{
+		desc: fmt.Sprintf("message~%q", substr),
+		match: func(entry *slf4go.EventEntry) bool {
+			return strings.Contains(entry.Message, substr)
+		},
+	}
+}
+
+// Logger match events from logger name
+func Logger(name string) Matcher {
+	return Matcher{
+		desc: fmt.Sprintf("logger=%s", name),
+		match: func(entry *slf4go.EventEntry) bool {
+			return entry.Source == name
+		},
+	}
+}
+
+// Attr match events with attribute, name can be given with or without the '@' prefix
+func Attr(name string, value interface{}) Matcher {
+	return Matcher{
+		desc: fmt.Sprintf("%s=%v", strings.TrimPrefix(name, "@"), value),
+		match: func(entry *slf4go.EventEntry) bool {
+			attr, ok := entry.Attrs[name]
+
+			if !ok {
+				attr, ok = entry.Attrs["@"+strings.TrimPrefix(name, "@")]
+			}
+
+			if !ok {
+				return false
+			}
+
+			return reflect.DeepEqual(attr, value) || fmt.Sprint(attr) == fmt.Sprint(value)
+		},
+	}
+}
+
+func describe(matchers []Matcher) string {
+	var descs []string
+
+	for _, matcher := range matchers {
+		descs = append(descs, matcher.String())
+	}
+
+	return "{" + strings.Join(descs, ", ") + "}"
+}
+
+// Filter get recorded events matching all matchers
+func (recorder *Recorder) Filter(matchers ...Matcher) []*slf4go.EventEntry {
+	var result []*slf4go.EventEntry
+
+	for _, entry := range recorder.Events() {
+		matched := true
+
+		for _, matcher := range matchers {
+			if !matcher.Match(entry) {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			result = append(result, entry)
+		}
+	}
+
+	return result
+}
+
+// Count count recorded events matching all matchers
+func (recorder *Recorder) Count(matchers ...Matcher) int {
+	return len(recorder.Filter(matchers...))
+}
+
+// CountByLevel count recorded events indexed by level
+func (recorder *Recorder) CountByLevel() map[slf4go.Level]int {
+	counts := make(map[slf4go.Level]int)
+
+	for _, entry := range recorder.Events() {
+		counts[entry.Level]++
+	}
+
+	return counts
+}
+
+// AssertLogged assert at least one recorded event matches all matchers
+func (recorder *Recorder) AssertLogged(t testing.TB, matchers ...Matcher) bool {
+	t.Helper()
+
+	if recorder.Count(matchers...) == 0 {
+		t.Errorf("expect event matching %s logged, recorded:\n%s", describe(matchers), recorder.dump())
+		return false
+	}
+
+	return true
+}
+
+// AssertNotLogged assert no recorded event matches all matchers
+func (recorder *Recorder) AssertNotLogged(t testing.TB, matchers ...Matcher) bool {
+	t.Helper()
+
+	if matched := recorder.Filter(matchers...); len(matched) != 0 {
+		t.Errorf("expect no event matching %s logged, got:\n%s", describe(matchers), dump(matched))
+		return false
+	}
+
+	return true
+}
+
+// AssertCount assert count of recorded events matching all matchers
+func (recorder *Recorder) AssertCount(t testing.TB, expect int, matchers ...Matcher) bool {
+	t.Helper()
+
+	if count := recorder.Count(matchers...); count != expect {
+		t.Errorf("expect %d event(s) matching %s, got %d, recorded:\n%s", expect, describe(matchers), count, recorder.dump())
+		return false
+	}
+
+	return true
+}
+
+// AssertNoErrors assert no ERROR event recorded
+func (recorder *Recorder) AssertNoErrors(t testing.TB) bool {
+	t.Helper()
+
+	return recorder.AssertNotLogged(t, Level(slf4go.ERROR))
+}
+
+func (recorder *Recorder) dump() string {
+	return dump(recorder.Events())
+}
+
+func dump(events []*slf4go.EventEntry) string {
+	var lines []string
+
+	for _, entry := range events {
+		lines = append(lines, "\t"+FormatEntry(entry))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// FormatEntry format event without timestamp and call frame, so the output is stable between runs
+func FormatEntry(entry *slf4go.EventEntry) string {
+	var keys []string
+
+	for key := range entry.Attrs {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var attrs []string
+
+	for _, key := range keys {
+		buff, err := json.Marshal(entry.Attrs[key])
+
+		if err != nil {
+			buff = []byte(fmt.Sprintf("%q", fmt.Sprint(entry.Attrs[key])))
+		}
+
+		attrs = append(attrs, fmt.Sprintf("%s=%s", strings.TrimPrefix(key, "@"), buff))
+	}
+
+	line := fmt.Sprintf("[%s] %s: %s", entry.Level, entry.Source, entry.Message)
+
+	if len(attrs) > 0 {
+		line = line + " " + strings.Join(attrs, " ")
+	}
+
+	return line
+}
@@ -0,0 +1,105 @@
+package slf4gotest
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/libs4go/slf4go"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTB struct {
+	testing.TB
+	failed bool
+}
+
+func (fake *fakeTB) Helper() {}
+
+func (fake *fakeTB) Errorf(format string, args ...interface{}) {
+	fake.failed = true
+}
+
+func (fake *fakeTB) Fatalf(format string, args ...interface{}) {
+	fake.failed = true
+}
+
+func TestRecorder(t *testing.T) {
+	factory, recorder := NewFactory(t)
+
+	logger := factory.Get("test")
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+			logger.I("user {@id} login", i)
+		}(i)
+	}
+
+	wg.Wait()
+
+	logger.W("disk {@usage} full", 99)
+
+	recorder.AssertCount(t, 10, Level(slf4go.INFO), Message("login"))
+	recorder.AssertLogged(t, Logger("test"), Attr("id", 3))
+	recorder.AssertLogged(t, Attr("@usage", 99))
+	recorder.AssertNoErrors(t)
+
+	require.Equal(t, map[slf4go.Level]int{slf4go.INFO: 10, slf4go.WARN: 1}, recorder.CountByLevel())
+
+	fake := &fakeTB{}
+	recorder.AssertLogged(fake, Level(slf4go.ERROR))
+	require.True(t, fake.failed)
+
+	fake = &fakeTB{}
+	recorder.AssertNotLogged(fake, Attr("usage", 99))
+	require.True(t, fake.failed)
+
+	recorder.Reset()
+	require.Empty(t, recorder.Events())
+}
+
+func TestGolden(t *testing.T) {
+	factory, recorder := NewFactory(t)
+
+	logger := factory.Get("golden")
+
+	logger.I("hello {@name}", "world")
+	logger.E("failed {@count} times", 3)
+
+	recorder.AssertGolden(t, "testdata/golden.txt")
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		return
+	}
+
+	logger.D("extra")
+
+	fake := &fakeTB{}
+	recorder.AssertGolden(fake, "testdata/golden.txt")
+	require.True(t, fake.failed)
+}
+
+func TestFixture(t *testing.T) {
+	config := LoadConfig(t, `
+name: test
+size: 10
+`)
+
+	require.Equal(t, "test", config.Get("name").String(""))
+	require.Equal(t, 10, config.Get("size").Int(0))
+
+	fake := &fakeTB{}
+	LoadConfig(fake, "name: [")
+	require.True(t, fake.failed)
+
+	entry := Entry(slf4go.WARN, "disk full", "usage", 99, "@host", "a", "dangling")
+
+	require.Equal(t, slf4go.WARN, entry.Level)
+	require.Equal(t, "test", entry.Source)
+	require.Equal(t, map[string]interface{}{"@usage": 99, "@host": "a"}, entry.Attrs)
+}
@@ -0,0 +1,31 @@
+package slf4gotest
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/libs4go/scf4go"
+	"github.com/libs4go/slf4go"
+)
+
+type testingBackend struct {
+	tb testing.TB
+}
+
+// NewTestingBackend create backend writing events through testing.TB.Log,
+// the output is displayed only for failing tests or with go test -v
+func NewTestingBackend(t testing.TB) slf4go.Backend {
+	return &testingBackend{tb: t}
+}
+
+func (backend *testingBackend) Send(entry *slf4go.EventEntry) {
+	backend.tb.Log(fmt.Sprintf("%s (%s:%d)", FormatEntry(entry), filepath.Base(entry.File), entry.Line))
+}
+
+func (backend *testingBackend) Sync() {
+}
+
+func (backend *testingBackend) Config(config scf4go.Config) error {
+	return nil
+}
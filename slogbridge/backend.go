@@ -0,0 +1,67 @@
+//go:build go1.21
+// +build go1.21
+
+package slogbridge
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/libs4go/scf4go"
+	"github.com/libs4go/slf4go"
+)
+
+type backend struct {
+	handler slog.Handler
+}
+
+// NewBackend create slf4go backend forwarding events to handler, the logger name is added
+// as 'logger' attribute and attribute keys are stripped of the '@' prefix. Don't pass slog.Default().Handler()
+// if the log package output is redirected into slf4go (stdlog.Redirect, slog.SetDefault), events would loop back
+func NewBackend(handler slog.Handler) slf4go.Backend {
+	return &backend{handler: handler}
+}
+
+func (backend *backend) Send(entry *slf4go.EventEntry) {
+	ctx := context.Background()
+	level := ToSlogLevel(entry.Level)
+
+	if !backend.handler.Enabled(ctx, level) {
+		return
+	}
+
+	record := slog.NewRecord(entry.Timestamp, level, entry.Message, 0)
+
+	var keys []string
+
+	for key := range entry.Attrs {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, 0, len(keys)+1)
+
+	if entry.Source != "" {
+		attrs = append(attrs, slog.String("logger", entry.Source))
+	}
+
+	for _, key := range keys {
+		attrs = append(attrs, slog.Any(strings.TrimPrefix(key, "@"), entry.Attrs[key]))
+	}
+
+	record.AddAttrs(attrs...)
+
+	if err := backend.handler.Handle(ctx, record); err != nil {
+		println("slog handler error: " + err.Error())
+	}
+}
+
+func (backend *backend) Sync() {
+}
+
+func (backend *backend) Config(config scf4go.Config) error {
+	return nil
+}
@@ -0,0 +1,3 @@
+// Package slogbridge connect slf4go with log/slog: Handler routes slog records into slf4go loggers,
+// Backend forwards slf4go events to any slog.Handler. The package requires go1.21 or later.
+package slogbridge
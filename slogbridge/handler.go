@@ -0,0 +1,154 @@
+//go:build go1.21
+// +build go1.21
+
+package slogbridge
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/libs4go/slf4go"
+)
+
+// Handler slog.Handler converting records into slf4go events, attribute keys are stored
+// with '@' prefix as template placeholders and groups are flattened with '.'
+type Handler struct {
+	logger slf4go.Logger
+	entry  slf4go.EntryLogger // nil if logger does not implement EntryLogger
+	prefix string             // group prefix of new attributes
+	attrs  map[string]interface{}
+}
+
+// NewHandler create slog.Handler routing records into logger, records are written through
+// the Logger level methods without attributes if logger does not implement slf4go.EntryLogger
+func NewHandler(logger slf4go.Logger) *Handler {
+	entry, _ := logger.(slf4go.EntryLogger)
+
+	return &Handler{
+		logger: logger,
+		entry:  entry,
+		attrs:  make(map[string]interface{}),
+	}
+}
+
+// Enabled .
+func (handler *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	if handler.entry == nil {
+		return true
+	}
+
+	return handler.entry.Enabled(FromSlogLevel(level))
+}
+
+// Handle .
+func (handler *Handler) Handle(ctx context.Context, record slog.Record) error {
+	if handler.entry == nil {
+		slf4go.LogAt(handler.logger, FromSlogLevel(record.Level), record.Message)
+		return nil
+	}
+
+	attrs := make(map[string]interface{}, len(handler.attrs)+record.NumAttrs())
+
+	for key, value := range handler.attrs {
+		attrs[key] = value
+	}
+
+	record.Attrs(func(attr slog.Attr) bool {
+		addAttr(attrs, handler.prefix, attr)
+		return true
+	})
+
+	timestamp := record.Time
+
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	entry := &slf4go.EventEntry{
+		Timestamp: timestamp,
+		Level:     FromSlogLevel(record.Level),
+		Message:   record.Message,
+		Template:  record.Message,
+		Attrs:     attrs,
+	}
+
+	if record.PC != 0 {
+		frame := slf4go.PCFrame(record.PC)
+
+		entry.File = frame.File
+		entry.Line = frame.Line
+		entry.Function = frame.Function
+	}
+
+	handler.entry.Send(entry)
+
+	return nil
+}
+
+// WithAttrs .
+func (handler *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return handler
+	}
+
+	clone := handler.clone()
+
+	for _, attr := range attrs {
+		addAttr(clone.attrs, clone.prefix, attr)
+	}
+
+	return clone
+}
+
+// WithGroup .
+func (handler *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return handler
+	}
+
+	clone := handler.clone()
+	clone.prefix = handler.prefix + name + "."
+
+	return clone
+}
+
+func (handler *Handler) clone() *Handler {
+	attrs := make(map[string]interface{}, len(handler.attrs))
+
+	for key, value := range handler.attrs {
+		attrs[key] = value
+	}
+
+	return &Handler{
+		logger: handler.logger,
+		entry:  handler.entry,
+		prefix: handler.prefix,
+		attrs:  attrs,
+	}
+}
+
+func addAttr(attrs map[string]interface{}, prefix string, attr slog.Attr) {
+	value := attr.Value.Resolve()
+
+	if value.Kind() == slog.KindGroup {
+		group := value.Group()
+
+		// groups with empty key are inlined
+		if attr.Key != "" {
+			prefix = prefix + attr.Key + "."
+		}
+
+		for _, child := range group {
+			addAttr(attrs, prefix, child)
+		}
+
+		return
+	}
+
+	if attr.Key == "" {
+		return
+	}
+
+	attrs["@"+prefix+attr.Key] = value.Any()
+}
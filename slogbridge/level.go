@@ -0,0 +1,45 @@
+//go:build go1.21
+// +build go1.21
+
+package slogbridge
+
+import (
+	"log/slog"
+
+	"github.com/libs4go/slf4go"
+)
+
+// LevelTrace slog level mapped from slf4go.TRACE
+const LevelTrace = slog.Level(-8)
+
+// FromSlogLevel map slog level into slf4go level, levels between two slog levels are rounded down
+func FromSlogLevel(level slog.Level) slf4go.Level {
+	switch {
+	case level < slog.LevelDebug:
+		return slf4go.TRACE
+	case level < slog.LevelInfo:
+		return slf4go.DEBUG
+	case level < slog.LevelWarn:
+		return slf4go.INFO
+	case level < slog.LevelError:
+		return slf4go.WARN
+	}
+
+	return slf4go.ERROR
+}
+
+// ToSlogLevel map slf4go level into slog level
+func ToSlogLevel(level slf4go.Level) slog.Level {
+	switch level {
+	case slf4go.TRACE:
+		return LevelTrace
+	case slf4go.DEBUG:
+		return slog.LevelDebug
+	case slf4go.INFO:
+		return slog.LevelInfo
+	case slf4go.WARN:
+		return slog.LevelWarn
+	}
+
+	return slog.LevelError
+}
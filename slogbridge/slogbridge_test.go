@@ -0,0 +1,118 @@
+//go:build go1.21
+// +build go1.21
+
+package slogbridge
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/libs4go/slf4go"
+	"github.com/libs4go/slf4go/slf4gotest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	factory, recorder := slf4gotest.NewFactory(t)
+
+	factory.ConfigDefault("recorder", slf4go.DEBUG)
+
+	logger := slog.New(NewHandler(factory.Get("slog")))
+
+	logger.Debug("debug", "a", 1)
+	logger.Log(context.Background(), LevelTrace, "trace")
+
+	logger.With("service", "api").WithGroup("req").With("id", 7).Info("request",
+		"path", "/", slog.Group("user", "name", "bob"), slog.Group("", "inline", true))
+
+	logger.Error("failed", "err", "boom")
+
+	events := recorder.Events()
+
+	require.Equal(t, 3, len(events))
+
+	require.Equal(t, slf4go.DEBUG, events[0].Level)
+	require.Equal(t, int64(1), events[0].Attrs["@a"])
+	require.Equal(t, "slog", events[0].Source)
+	require.Equal(t, "slogbridge_test.go", filepath.Base(events[0].File))
+
+	require.Equal(t, "request", events[1].Message)
+	require.Equal(t, map[string]interface{}{
+		"@service":       "api",
+		"@req.id":        int64(7),
+		"@req.path":      "/",
+		"@req.user.name": "bob",
+		"@req.inline":    true,
+	}, events[1].Attrs)
+
+	require.Equal(t, slf4go.ERROR, events[2].Level)
+}
+
+// plainLogger Logger without EntryLogger
+type plainLogger struct {
+	messages []string
+}
+
+func (logger *plainLogger) Name() string { return "plain" }
+
+func (logger *plainLogger) T(message string, args ...interface{}) {
+	logger.messages = append(logger.messages, "T "+message)
+}
+
+func (logger *plainLogger) D(message string, args ...interface{}) {
+	logger.messages = append(logger.messages, "D "+message)
+}
+
+func (logger *plainLogger) I(message string, args ...interface{}) {
+	logger.messages = append(logger.messages, "I "+message)
+}
+
+func (logger *plainLogger) W(message string, args ...interface{}) {
+	logger.messages = append(logger.messages, "W "+message)
+}
+
+func (logger *plainLogger) E(message string, args ...interface{}) {
+	logger.messages = append(logger.messages, "E "+message)
+}
+
+func TestPlainLogger(t *testing.T) {
+	plain := &plainLogger{}
+
+	logger := slog.New(NewHandler(plain))
+
+	logger.With("a", 1).Warn("disk {@usage} full", "usage", 99)
+	logger.Error("failed")
+
+	require.Equal(t, []string{"W disk { @usage} full", "E failed"}, plain.messages)
+}
+
+func TestLevels(t *testing.T) {
+	for _, level := range []slf4go.Level{slf4go.TRACE, slf4go.DEBUG, slf4go.INFO, slf4go.WARN, slf4go.ERROR} {
+		require.Equal(t, level, FromSlogLevel(ToSlogLevel(level)))
+	}
+
+	require.Equal(t, slf4go.INFO, FromSlogLevel(slog.LevelInfo+2))
+	require.Equal(t, slf4go.ERROR, FromSlogLevel(slog.LevelError+4))
+}
+
+func TestBackend(t *testing.T) {
+	var buff bytes.Buffer
+
+	factory := slf4go.New()
+	factory.RegisterBackend("slog", NewBackend(slog.NewTextHandler(&buff, &slog.HandlerOptions{Level: slog.LevelInfo})))
+	factory.ConfigDefault("slog", slf4go.TRACE)
+
+	logger := factory.Get("test")
+
+	logger.D("hidden")
+	logger.W("disk {@usage} full", 99)
+
+	output := buff.String()
+
+	require.Equal(t, 1, strings.Count(output, "\n"))
+	require.Contains(t, output, `level=WARN msg="disk 99 full" logger=test usage=99`)
+}
@@ -0,0 +1,4 @@
+// Package stdlog bridge the standard library log package into slf4go loggers. The package requires
+// go1.21 or later: older log.Logger holds its lock while calling Write, so reading flags and prefix
+// inside Write would deadlock.
+package stdlog
@@ -0,0 +1,143 @@
+//go:build go1.21
+// +build go1.21
+
+package stdlog
+
+import (
+	"log"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/libs4go/slf4go"
+)
+
+type writer struct {
+	logger slf4go.Logger
+	level  slf4go.Level
+	flags  func() (int, string) // current flags and prefix of the bridged log.Logger
+}
+
+// New create *log.Logger writing into logger with level, the returned logger's prefix and flags
+// can be changed as usual and are stripped from the messages
+func New(logger slf4go.Logger, level slf4go.Level) *log.Logger {
+	writer := &writer{
+		logger: logger,
+		level:  level,
+	}
+
+	std := log.New(writer, "", 0)
+
+	writer.flags = func() (int, string) {
+		return std.Flags(), std.Prefix()
+	}
+
+	return std
+}
+
+// Redirect redirect the standard log package output into logger with level,
+// call the returned function to restore the previous output
+func Redirect(logger slf4go.Logger, level slf4go.Level) func() {
+	writer := &writer{
+		logger: logger,
+		level:  level,
+		flags: func() (int, string) {
+			return log.Flags(), log.Prefix()
+		},
+	}
+
+	previous := log.Writer()
+
+	log.SetOutput(writer)
+
+	return func() {
+		log.SetOutput(previous)
+	}
+}
+
+func isBridgeFrame(function string) bool {
+	return strings.HasPrefix(function, "log.") ||
+		strings.HasPrefix(function, "github.com/libs4go/slf4go/stdlog.(*writer).")
+}
+
+func skipField(line string) string {
+	if index := strings.IndexByte(line, ' '); index != -1 {
+		return line[index+1:]
+	}
+
+	return line
+}
+
+// stripHeader remove prefix, date, time and file header written by log.Logger
+func stripHeader(line string, flags int, prefix string) string {
+	if flags&log.Lmsgprefix == 0 {
+		line = strings.TrimPrefix(line, prefix)
+	}
+
+	if flags&log.Ldate != 0 {
+		line = skipField(line)
+	}
+
+	if flags&(log.Ltime|log.Lmicroseconds) != 0 {
+		line = skipField(line)
+	}
+
+	if flags&(log.Lshortfile|log.Llongfile) != 0 {
+		if index := strings.Index(line, ": "); index != -1 {
+			line = line[index+2:]
+		}
+	}
+
+	if flags&log.Lmsgprefix != 0 {
+		line = strings.TrimPrefix(line, prefix)
+	}
+
+	return line
+}
+
+func (writer *writer) Write(p []byte) (int, error) {
+	entryLogger, ok := writer.logger.(slf4go.EntryLogger)
+
+	if ok && !entryLogger.Enabled(writer.level) {
+		return len(p), nil
+	}
+
+	flags, prefix := writer.flags()
+
+	lines := strings.Split(strings.TrimSuffix(string(p), "\n"), "\n")
+
+	var frame runtime.Frame
+
+	if ok {
+		frame = slf4go.CallerFrame(isBridgeFrame)
+	}
+
+	for i, line := range lines {
+		if i == 0 {
+			line = stripHeader(line, flags, prefix)
+		}
+
+		line = strings.TrimSuffix(line, "\r")
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if !ok {
+			slf4go.LogAt(writer.logger, writer.level, line)
+			continue
+		}
+
+		entryLogger.Send(&slf4go.EventEntry{
+			Timestamp: time.Now(),
+			Level:     writer.level,
+			Message:   line,
+			Attrs:     make(map[string]interface{}),
+			File:      frame.File,
+			Line:      frame.Line,
+			Function:  frame.Function,
+		})
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,63 @@
+//go:build go1.21
+// +build go1.21
+
+package stdlog
+
+import (
+	"log"
+	"path/filepath"
+	"testing"
+
+	"github.com/libs4go/slf4go"
+	"github.com/libs4go/slf4go/slf4gotest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	factory, recorder := slf4gotest.NewFactory(t)
+
+	std := New(factory.Get("std"), slf4go.WARN)
+	std.SetPrefix("[lib] ")
+	std.SetFlags(log.LstdFlags | log.Lmicroseconds | log.Lshortfile)
+
+	std.Printf("hello {@name} %d", 1)
+	std.Print("first\nsecond\n\n")
+
+	std.SetFlags(log.LstdFlags | log.Lmsgprefix)
+	std.Print("message prefix")
+
+	events := recorder.Events()
+
+	require.Equal(t, 4, len(events))
+	require.Equal(t, "hello {@name} 1", events[0].Message)
+	require.Equal(t, slf4go.WARN, events[0].Level)
+	require.Equal(t, "std", events[0].Source)
+	require.Equal(t, "stdlog_test.go", filepath.Base(events[0].File))
+	require.Equal(t, "github.com/libs4go/slf4go/stdlog.TestNew", events[0].Function)
+	require.Equal(t, "first", events[1].Message)
+	require.Equal(t, "second", events[2].Message)
+	require.Equal(t, "message prefix", events[3].Message)
+}
+
+func TestRedirect(t *testing.T) {
+	factory, recorder := slf4gotest.NewFactory(t)
+
+	factory.ConfigDefault("recorder", slf4go.INFO)
+
+	restore := Redirect(factory.Get("std"), slf4go.INFO)
+
+	log.Println("redirected")
+
+	restore()
+
+	redirect := Redirect(factory.Get("std"), slf4go.DEBUG)
+
+	log.Println("disabled")
+
+	redirect()
+
+	recorder.AssertCount(t, 1, slf4gotest.Message("redirected"), slf4gotest.Level(slf4go.INFO))
+	recorder.AssertNotLogged(t, slf4gotest.Message("disabled"))
+
+	require.Equal(t, "github.com/libs4go/slf4go/stdlog.TestRedirect", recorder.Events()[0].Function)
+}
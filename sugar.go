@@ -0,0 +1,179 @@
+package slf4go
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SugaredLogger wrapper of Logger with printf-style and key-value methods, it never panics on bad arguments
+type SugaredLogger struct {
+	logger Logger
+}
+
+// Sugar wrap logger with printf-style and key-value methods
+func Sugar(logger Logger) *SugaredLogger {
+	return &SugaredLogger{logger: logger}
+}
+
+// Logger get the wrapped logger
+func (sugar *SugaredLogger) Logger() Logger {
+	return sugar.logger
+}
+
+// Name .
+func (sugar *SugaredLogger) Name() string {
+	return sugar.logger.Name()
+}
+
+func isSugarFrame(function string) bool {
+	return strings.HasPrefix(function, "github.com/libs4go/slf4go.(*SugaredLogger).")
+}
+
+// badKey attribute name of values without a string key
+const badKey = "!BADKEY"
+
+// keyValues convert key-value pairs into attributes, a value without string key is stored
+// as !BADKEY attribute and consumes only one argument
+func keyValues(keysAndValues []interface{}) map[string]interface{} {
+	attrs := make(map[string]interface{}, len(keysAndValues)/2)
+
+	bad := 0
+
+	for i := 0; i < len(keysAndValues); {
+		key, ok := keysAndValues[i].(string)
+
+		if !ok || i+1 == len(keysAndValues) {
+			name := badKey
+
+			if bad > 0 {
+				name = badKey + strconv.Itoa(bad)
+			}
+
+			bad++
+
+			attrs["@"+name] = keysAndValues[i]
+			i++
+
+			continue
+		}
+
+		attrs["@"+strings.TrimPrefix(key, "@")] = keysAndValues[i+1]
+		i += 2
+	}
+
+	return attrs
+}
+
+// renderAttrs replace message placeholders having attributes
+func renderAttrs(message string, attrs map[string]interface{}) string {
+	return messageRegx.ReplaceAllStringFunc(message, func(placeholder string) string {
+		value, ok := attrs[strings.TrimSuffix(strings.TrimPrefix(placeholder, "{"), "}")]
+
+		if !ok {
+			return placeholder
+		}
+
+		data, err := formatArg(value)
+
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+
+		return data
+	})
+}
+
+func (sugar *SugaredLogger) log(level Level, template string, render func() (string, map[string]interface{})) {
+	entryLogger, ok := sugar.logger.(EntryLogger)
+
+	if !ok {
+		message, _ := render()
+		LogAt(sugar.logger, level, message)
+		return
+	}
+
+	if !entryLogger.Enabled(level) {
+		return
+	}
+
+	message, attrs := render()
+
+	frame := CallerFrame(isSugarFrame)
+
+	entryLogger.Send(&EventEntry{
+		Timestamp: time.Now(),
+		Level:     level,
+		Message:   message,
+		Template:  template,
+		Attrs:     attrs,
+		File:      frame.File,
+		Line:      frame.Line,
+		Function:  frame.Function,
+	})
+}
+
+func (sugar *SugaredLogger) logf(level Level, format string, args []interface{}) {
+	sugar.log(level, format, func() (string, map[string]interface{}) {
+		return fmt.Sprintf(format, args...), make(map[string]interface{})
+	})
+}
+
+func (sugar *SugaredLogger) logw(level Level, message string, keysAndValues []interface{}) {
+	sugar.log(level, message, func() (string, map[string]interface{}) {
+		attrs := keyValues(keysAndValues)
+
+		return renderAttrs(message, attrs), attrs
+	})
+}
+
+// Tf log TRACE event with fmt.Sprintf message
+func (sugar *SugaredLogger) Tf(format string, args ...interface{}) {
+	sugar.logf(TRACE, format, args)
+}
+
+// Df log DEBUG event with fmt.Sprintf message
+func (sugar *SugaredLogger) Df(format string, args ...interface{}) {
+	sugar.logf(DEBUG, format, args)
+}
+
+// If log INFO event with fmt.Sprintf message
+func (sugar *SugaredLogger) If(format string, args ...interface{}) {
+	sugar.logf(INFO, format, args)
+}
+
+// Wf log WARN event with fmt.Sprintf message
+func (sugar *SugaredLogger) Wf(format string, args ...interface{}) {
+	sugar.logf(WARN, format, args)
+}
+
+// Ef log ERROR event with fmt.Sprintf message
+func (sugar *SugaredLogger) Ef(format string, args ...interface{}) {
+	sugar.logf(ERROR, format, args)
+}
+
+// Tw log TRACE event with key-value attributes, {@key} placeholders in message are rendered
+func (sugar *SugaredLogger) Tw(message string, keysAndValues ...interface{}) {
+	sugar.logw(TRACE, message, keysAndValues)
+}
+
+// Dw log DEBUG event with key-value attributes, {@key} placeholders in message are rendered
+func (sugar *SugaredLogger) Dw(message string, keysAndValues ...interface{}) {
+	sugar.logw(DEBUG, message, keysAndValues)
+}
+
+// Iw log INFO event with key-value attributes, {@key} placeholders in message are rendered
+func (sugar *SugaredLogger) Iw(message string, keysAndValues ...interface{}) {
+	sugar.logw(INFO, message, keysAndValues)
+}
+
+// Ww log WARN event with key-value attributes, {@key} placeholders in message are rendered
+func (sugar *SugaredLogger) Ww(message string, keysAndValues ...interface{}) {
+	sugar.logw(WARN, message, keysAndValues)
+}
+
+// Ew log ERROR event with key-value attributes, {@key} placeholders in message are rendered
+func (sugar *SugaredLogger) Ew(message string, keysAndValues ...interface{}) {
+	sugar.logw(ERROR, message, keysAndValues)
+}